@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"coldcase/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show and edit ~/.coldcase/config.yaml",
+		Long: `Show and edit the ColdCase configuration.
+
+Settings resolve as flag > env > profile > shared settings > default.
+Use the global --profile flag to pick the profile that 'config show' and
+'config get' resolve, and that 'config set' writes to.`,
+		// A broken config.yaml or a new --profile must not prevent 'config set';
+		// show and get report the error through mustConfig.
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			configErr = applyGlobalFlags()
+		},
+	}
+
+	configCmd.AddCommand(
+		configShowCmd(),
+		configGetCmd(),
+		configSetCmd(),
+	)
+
+	rootCmd.AddCommand(configCmd)
+}
+
+// ─── show ─────────────────────────────────────────────────────────────────────
+
+func configShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "Show every resolved setting and where it came from",
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := mustConfig()
+			f, err := config.ReadFile()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Config file  : %s\n", config.Path())
+			profile := cfg.Profile
+			if profile == "" {
+				profile = "(none)"
+			}
+			fmt.Printf("Profile      : %s\n", profile)
+			if names := f.ProfileNames(); len(names) > 0 {
+				fmt.Printf("Profiles     : %s\n", strings.Join(names, ", "))
+			}
+			fmt.Println()
+			for _, k := range config.Keys {
+				val := cfg.Get(k.Name)
+				if val == "" {
+					val = "(auto)"
+				}
+				fmt.Printf("  %-15s %-45s [%s]\n", k.Name, val, cfg.Source(k.Name))
+			}
+		},
+	}
+}
+
+// ─── get ──────────────────────────────────────────────────────────────────────
+
+func configGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print the resolved value of a setting",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := mustConfig()
+			if args[0] == config.ProfileKey {
+				fmt.Println(cfg.Profile)
+				return
+			}
+			if _, ok := config.Lookup(args[0]); !ok {
+				fmt.Fprintf(os.Stderr, "Error: unknown setting %q (valid: %s, %s)\n", args[0], config.ProfileKey, strings.Join(config.Names(), ", "))
+				os.Exit(1)
+			}
+			fmt.Println(cfg.Get(args[0]))
+		},
+	}
+}
+
+// ─── set ──────────────────────────────────────────────────────────────────────
+
+func configSetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Store a setting in the shared settings or in --profile",
+		Long: `Store a setting in config.yaml. Without --profile the value goes into the
+shared settings used by every profile. An empty value removes the setting.
+
+'config set profile <name>' selects the default profile.`,
+		Example: `  coldcase config set suite_path /opt/DidierStevensSuite
+  coldcase --profile airgapped config set runtime podman
+  coldcase config set profile field`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			f, err := config.ReadFile()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			profile, _ := rootCmd.PersistentFlags().GetString("profile")
+			if err := f.Set(profile, args[0], args[1]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := f.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", config.Path(), err)
+				os.Exit(1)
+			}
+
+			where := "shared settings"
+			switch {
+			case args[0] == config.ProfileKey:
+				where = "default profile"
+			case profile != "":
+				where = "profile " + profile
+			}
+			if args[1] == "" {
+				fmt.Printf("[*] Removed %s from %s\n", args[0], where)
+			} else {
+				fmt.Printf("[*] Set %s = %s in %s\n", args[0], args[1], where)
+			}
+		},
+	}
+}
+
+// configErr is the resolution error seen by the config command, if any.
+var configErr error
+
+// mustConfig returns the resolved configuration or exits.
+func mustConfig() *config.Config {
+	if configErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", configErr)
+		os.Exit(1)
+	}
+	return activeConfig
+}
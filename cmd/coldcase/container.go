@@ -1,11 +1,15 @@
 package main
 
 import (
+	"crypto/ed25519"
 	"fmt"
 	"os"
 	"os/exec"
+	"sort"
 
+	"coldcase/pkg/bundle"
 	"coldcase/pkg/runner"
+	"coldcase/pkg/session"
 
 	"github.com/spf13/cobra"
 )
@@ -22,6 +26,8 @@ func init() {
 		containerBuildCmd(),
 		containerPullCmd(),
 		containerShellCmd(),
+		containerExportCmd(),
+		containerImportCmd(),
 	)
 
 	rootCmd.AddCommand(containerCmd)
@@ -152,3 +158,101 @@ The current directory is bind-mounted at /data inside the container.`,
 	cmd.Flags().StringVarP(&workdir, "workdir", "w", "", "Host directory to mount at /data (default: current dir)")
 	return cmd
 }
+
+// ─── export ───────────────────────────────────────────────────────────────────
+
+func containerExportCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "export <file.tar>",
+		Short: "Save the container image to a signed bundle for air-gapped machines",
+		Long: `Save the ColdCase image with 'docker save' into a bundle that also carries a
+manifest signed with the investigator key: the image digest and ID, the
+version of every registered tool inside the image, and the SHA-256 of the
+image tarball. Load it on the offline machine with 'coldcase container import'.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			rt := runner.DetectedRuntime()
+			if rt == "" {
+				fmt.Fprintln(os.Stderr, "Error: no container runtime found")
+				os.Exit(1)
+			}
+			priv, err := session.LoadPrivateKey()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: bundles are signed with the investigator key: %v\n", err)
+				fmt.Fprintln(os.Stderr, "       Run: coldcase keys generate")
+				os.Exit(1)
+			}
+			image := runner.ImageName()
+			fmt.Printf("Exporting %s with %s...\n", image, rt)
+			m, err := bundle.Export(rt, image, args[0], toolRegistry.Binaries(), priv)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+				os.Exit(1)
+			}
+			printBundle(m)
+			fmt.Printf("[*] Bundle written to %s\n", args[0])
+		},
+	}
+}
+
+// ─── import ───────────────────────────────────────────────────────────────────
+
+func containerImportCmd() *cobra.Command {
+	var keyFile string
+	cmd := &cobra.Command{
+		Use:   "import <file.tar>",
+		Short: "Verify and load a bundle written by 'container export'",
+		Long: `Check a bundle's manifest signature and image tarball hash, then load the
+image with 'docker load'. The signer must be the local investigator key or the
+public key given with --key (as written by 'coldcase keys export').`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			rt := runner.DetectedRuntime()
+			if rt == "" {
+				fmt.Fprintln(os.Stderr, "Error: no container runtime found")
+				os.Exit(1)
+			}
+			var pub ed25519.PublicKey
+			var err error
+			if keyFile != "" {
+				var data []byte
+				if data, err = os.ReadFile(keyFile); err == nil {
+					pub, _, err = session.ParseKey(data)
+				}
+			} else {
+				pub, err = session.LoadPublicKey()
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading trusted key: %v\n", err)
+				os.Exit(1)
+			}
+			m, err := bundle.Import(rt, args[0], pub)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[x] Import refused: %v\n", err)
+				os.Exit(1)
+			}
+			printBundle(m)
+			fmt.Printf("[*] Signature and tarball hash verified; %s loaded\n", m.Image)
+		},
+	}
+	cmd.Flags().StringVar(&keyFile, "key", "", "Public key file of the bundle signer (default: local investigator key)")
+	return cmd
+}
+
+// printBundle summarises a bundle manifest.
+func printBundle(m *bundle.Manifest) {
+	fmt.Printf("[*] Image    : %s\n", m.Image)
+	fmt.Printf("[*] Digest   : %s\n", m.Digest)
+	fmt.Printf("[*] Image ID : %s\n", m.ImageID)
+	fmt.Printf("[*] Tarball  : sha256:%s (%d bytes)\n", m.TarballSHA256, m.TarballSize)
+	fmt.Printf("[*] Signer   : %s\n", m.Signer)
+	names := make([]string, 0, len(m.ToolVersions))
+	for name := range m.ToolVersions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Printf("[*] Tools    : %d versioned\n", len(names))
+	for _, name := range names {
+		fmt.Printf("      %-20s %s\n", name, m.ToolVersions[name])
+	}
+}
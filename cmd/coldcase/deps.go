@@ -98,14 +98,14 @@ func installDependencies(cmd *cobra.Command, args []string) {
 }
 
 func installPythonDeps(cmd *cobra.Command, args []string) {
-	if _, err := os.Stat("./volatility3"); os.IsNotExist(err) {
-		fmt.Println("[!] Volatility3 directory not found")
+	if _, err := os.Stat(vol3.VolDir); os.IsNotExist(err) {
+		fmt.Printf("[!] Volatility3 directory not found: %s\n", vol3.VolDir)
 		return
 	}
 	fmt.Println("Installing Volatility3 Python dependencies...")
 	if tools.CheckToolInstalled("uv") {
 		fmt.Println("Using uv for faster installation...")
-		if err := tools.ExecuteCommand("uv", "pip", "install", "-e", "./volatility3[full]"); err != nil {
+		if err := tools.ExecuteCommand("uv", "pip", "install", "-e", vol3.VolDir+"[full]"); err != nil {
 			fmt.Printf("[!] uv install failed, falling back to pip: %v\n", err)
 			pipInstall()
 		}
@@ -115,9 +115,9 @@ func installPythonDeps(cmd *cobra.Command, args []string) {
 }
 
 func pipInstall() {
-	if err := tools.ExecuteCommand("python3", "-m", "pip", "install", "-e", "./volatility3[full]"); err != nil {
+	if err := tools.ExecuteCommand("python3", "-m", "pip", "install", "-e", vol3.VolDir+"[full]"); err != nil {
 		fmt.Printf("[x] Python dependency installation failed: %v\n", err)
-		fmt.Println("Manual: cd volatility3 && python3 -m pip install -e .[full]")
+		fmt.Printf("Manual: cd %s && python3 -m pip install -e .[full]\n", vol3.VolDir)
 	}
 }
 
@@ -125,22 +125,18 @@ func checkDeps(cmd *cobra.Command, args []string) {
 	fmt.Println("Checking for missing dependencies...")
 	missing := false
 
-	for _, t := range []string{"python3", "exiftool", "binwalk", "fls", "fsstat", "istat"} {
-		if !tools.CheckToolInstalled(t) {
-			fmt.Printf("[x] Missing: %s\n", t)
+	for _, bin := range toolRegistry.Binaries() {
+		if !tools.CheckToolInstalled(bin) {
+			fmt.Printf("[x] Missing: %s\n", bin)
 			missing = true
 		}
 	}
-	for dep, ok := range vol3.CheckDependencies() {
-		if !ok {
-			fmt.Printf("[x] Missing: %s\n", dep)
+	for _, dep := range toolRegistry.Dependencies() {
+		if !dep.Check() {
+			fmt.Printf("[x] Missing: %s\n", dep.Name)
 			missing = true
 		}
 	}
-	if _, err := os.Stat("./DidierStevensSuite"); os.IsNotExist(err) {
-		fmt.Println("[x] Missing: DidierStevensSuite directory")
-		missing = true
-	}
 	if !missing {
 		fmt.Println("[*] All dependencies are installed!")
 	} else {
@@ -151,12 +147,12 @@ func checkDeps(cmd *cobra.Command, args []string) {
 func updateDeps(cmd *cobra.Command, args []string) {
 	fmt.Println("Updating Python dependencies...")
 	if tools.CheckToolInstalled("uv") {
-		if err := tools.ExecuteCommand("uv", "pip", "install", "--upgrade", "./volatility3[full]"); err != nil {
+		if err := tools.ExecuteCommand("uv", "pip", "install", "--upgrade", vol3.VolDir+"[full]"); err != nil {
 			fmt.Printf("[!] uv update failed, falling back to pip: %v\n", err)
-			tools.ExecuteCommand("python3", "-m", "pip", "install", "--upgrade", "./volatility3[full]")
+			tools.ExecuteCommand("python3", "-m", "pip", "install", "--upgrade", vol3.VolDir+"[full]")
 		}
 	} else {
-		if err := tools.ExecuteCommand("python3", "-m", "pip", "install", "--upgrade", "./volatility3[full]"); err != nil {
+		if err := tools.ExecuteCommand("python3", "-m", "pip", "install", "--upgrade", vol3.VolDir+"[full]"); err != nil {
 			fmt.Printf("[x] Update failed: %v\n", err)
 		}
 	}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"coldcase/pkg/session"
+
+	"github.com/spf13/cobra"
+)
+
+// evidenceSession is the --session flag shared by all evidence commands.
+var evidenceSession string
+
+func init() {
+	evidenceCmd := &cobra.Command{
+		Use:   "evidence",
+		Short: "Register and inspect case evidence",
+		Long: `Register evidence with a session and inspect what is registered.
+Registered evidence gets an ID such as EV-001; pass it to any tool as @EV-001
+and the runner substitutes the evidence path.`,
+	}
+
+	evidenceCmd.AddCommand(
+		evidenceAddCmd(),
+		evidenceListCmd(),
+		evidenceVerifyCmd(),
+	)
+	evidenceCmd.PersistentFlags().StringVar(&evidenceSession, "session", "", "Session ID (default: $COLDCASE_SESSION_ID)")
+	evidenceCmd.PersistentFlags().StringVar(&passphraseFile, "passphrase-file", "",
+		"Read the encrypted-session passphrase from this file (default: $"+session.EnvPassphrase+" or prompt)")
+
+	rootCmd.AddCommand(evidenceCmd)
+}
+
+// mustLoadEvidenceSession loads the session named by --session, or the active one.
+func mustLoadEvidenceSession() (*session.Manager, *session.Session) {
+	var args []string
+	if evidenceSession != "" {
+		args = []string{evidenceSession}
+	}
+	return mustLoadSession(args)
+}
+
+// ─── add ──────────────────────────────────────────────────────────────────────
+
+func evidenceAddCmd() *cobra.Command {
+	var opts session.AcquireOpts
+	cmd := &cobra.Command{
+		Use:   "add <path>",
+		Short: "Hash a file (MD5, SHA-1, SHA-256) and register it as evidence",
+		Long: `Hash a file with MD5, SHA-1 and SHA-256 in a single pass, record its
+acquisition metadata in the session journal and assign it an evidence ID.
+With --copy the file is also copied, in the same pass, into the session's
+case store; tools referring to the evidence by ID then read the copy.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			m, s := mustLoadEvidenceSession()
+			opts.AcquiredBy = currentUsername()
+			ev, err := m.AddEvidence(s, args[0], opts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "[*] Registered %s in session '%s'\n", ev.ID, s.ID)
+			printEvidence(m, s, ev)
+		},
+	}
+	cmd.Flags().StringVar(&opts.Label, "label", "", "Short description, e.g. \"suspect laptop disk image\"")
+	cmd.Flags().StringVar(&opts.Source, "source", "", "Where the evidence was acquired from")
+	cmd.Flags().StringVar(&opts.Custodian, "custodian", "", "Who handed the evidence over")
+	cmd.Flags().BoolVar(&opts.Copy, "copy", false, "Copy the file into the session's case store")
+	return cmd
+}
+
+// ─── list ─────────────────────────────────────────────────────────────────────
+
+func evidenceListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List registered evidence",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			_, s := mustLoadEvidenceSession()
+			n := 0
+			for _, ev := range s.Evidence {
+				if ev.ID == "" {
+					continue
+				}
+				n++
+				fmt.Printf("%-7s  %s  %12d  %s", ev.ID, ev.SHA256, ev.Size, ev.OriginalPath)
+				if ev.Label != "" {
+					fmt.Printf("  (%s)", ev.Label)
+				}
+				fmt.Println()
+			}
+			if n == 0 {
+				fmt.Println("No evidence registered. Use 'coldcase evidence add <path>'.")
+			}
+		},
+	}
+}
+
+// ─── verify ───────────────────────────────────────────────────────────────────
+
+func evidenceVerifyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify [EV-nnn...]",
+		Short: "Re-hash registered evidence and compare it with the acquisition hashes",
+		Long: `Re-hash every registered evidence file, or only the given IDs, in full and
+compare MD5, SHA-1, SHA-256 and size with the values recorded at intake.
+Both the original and the case store copy are checked. A missing original
+is only a warning when the case store copy still verifies.
+Exits with status 1 if any evidence fails to verify.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			m, s := mustLoadEvidenceSession()
+			for _, id := range args {
+				if _, ok := s.EvidenceByID(id); !ok {
+					fmt.Fprintf(os.Stderr, "Error: unknown evidence ID %s in session '%s'\n", id, s.ID)
+					os.Exit(1)
+				}
+			}
+
+			checks := m.VerifyEvidence(s, args...)
+			storedOK := map[string]bool{}
+			for _, c := range checks {
+				if c.Stored && c.Status == session.EvidenceOK {
+					storedOK[c.ID] = true
+				}
+			}
+			// Each item may be checked twice (original and copy); count items.
+			seen, failed := map[string]bool{}, map[string]bool{}
+			for _, c := range checks {
+				seen[c.ID] = true
+				what := "original"
+				if c.Stored {
+					what = "copy"
+				}
+				switch {
+				case c.Status == session.EvidenceOK:
+					fmt.Printf("[*] %-7s  %-8s  OK        %s\n", c.ID, what, c.Path)
+				case c.Status == session.EvidenceMissing && !c.Stored && storedOK[c.ID]:
+					fmt.Printf("[!] %-7s  %-8s  MISSING   %s (case store copy verified)\n", c.ID, what, c.Path)
+				case c.Status == session.EvidenceModified:
+					failed[c.ID] = true
+					fmt.Printf("[x] %-7s  %-8s  MODIFIED  %s (%s differ)\n", c.ID, what, c.Path, c.Mismatch)
+				default:
+					failed[c.ID] = true
+					fmt.Printf("[x] %-7s  %-8s  %-8s  %s: %v\n", c.ID, what, strings.ToUpper(c.Status), c.Path, c.Err)
+				}
+			}
+			if len(seen) == 0 {
+				fmt.Println("No evidence registered. Use 'coldcase evidence add <path>'.")
+				return
+			}
+			if len(failed) > 0 {
+				fmt.Fprintf(os.Stderr, "[x] %d of %d evidence item(s) failed verification in session '%s'\n", len(failed), len(seen), s.ID)
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "[*] All evidence matches its acquisition hashes in session '%s'\n", s.ID)
+		},
+	}
+}
+
+// ─── helpers ──────────────────────────────────────────────────────────────────
+
+func printEvidence(m *session.Manager, s *session.Session, ev *session.EvidenceFile) {
+	fmt.Printf("ID         : %s\n", ev.ID)
+	fmt.Printf("Path       : %s\n", ev.OriginalPath)
+	if ev.StoredPath != "" {
+		fmt.Printf("Stored     : %s\n", m.EvidencePath(s, ev))
+	}
+	fmt.Printf("Size       : %d bytes\n", ev.Size)
+	fmt.Printf("MD5        : %s\n", ev.MD5)
+	fmt.Printf("SHA-1      : %s\n", ev.SHA1)
+	fmt.Printf("SHA-256    : %s\n", ev.SHA256)
+	fmt.Printf("Acquired   : %s by %s\n", ev.CapturedAt.Format("2006-01-02 15:04:05"), ev.AcquiredBy)
+	for _, f := range []struct{ name, v string }{{"Label", ev.Label}, {"Source", ev.Source}, {"Custodian", ev.Custodian}} {
+		if f.v != "" {
+			fmt.Printf("%-11s: %s\n", f.name, f.v)
+		}
+	}
+}
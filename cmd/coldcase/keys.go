@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"coldcase/pkg/session"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	keysCmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage the investigator Ed25519 signing keys",
+		Long:  "Generate, inspect, export and import the Ed25519 keypair stored under ~/.coldcase/keys",
+	}
+
+	keysCmd.AddCommand(
+		keysGenerateCmd(),
+		keysShowCmd(),
+		keysExportCmd(),
+		keysImportCmd(),
+		keysFingerprintCmd(),
+	)
+
+	rootCmd.AddCommand(keysCmd)
+}
+
+// ─── generate ─────────────────────────────────────────────────────────────────
+
+func keysGenerateCmd() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a new investigator keypair",
+		Run: func(cmd *cobra.Command, args []string) {
+			if session.KeysExist() && !force {
+				fmt.Fprintf(os.Stderr, "Error: investigator key already exists at %s\n", session.PrivateKeyPath())
+				fmt.Fprintln(os.Stderr, "       Use --force to replace it (existing signed sessions will no longer verify).")
+				os.Exit(1)
+			}
+			pub, priv, err := session.GenerateKeys()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Key generation failed: %v\n", err)
+				os.Exit(1)
+			}
+			if err := session.SaveKeys(pub, priv, force); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("[*] Keypair written to %s\n", session.KeysDir())
+			fmt.Printf("[*] Fingerprint: %s\n", session.Fingerprint(pub))
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing keypair")
+	return cmd
+}
+
+// ─── show ─────────────────────────────────────────────────────────────────────
+
+func keysShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "Show key locations and the public key",
+		Run: func(cmd *cobra.Command, args []string) {
+			pub, err := session.LoadPublicKey()
+			if err != nil {
+				fmt.Println("[x] No investigator key found. Run: coldcase keys generate")
+				return
+			}
+			fmt.Printf("Public key   : %s\n", session.PublicKeyPath())
+			if session.KeysExist() {
+				fmt.Printf("Private key  : %s\n", session.PrivateKeyPath())
+			} else {
+				fmt.Println("Private key  : (none — verify-only keyring)")
+			}
+			fmt.Printf("Fingerprint  : %s\n", session.Fingerprint(pub))
+			fmt.Printf("Key (hex)    : %s\n", hex.EncodeToString(pub))
+		},
+	}
+}
+
+// ─── export ───────────────────────────────────────────────────────────────────
+
+func keysExportCmd() *cobra.Command {
+	var output string
+	var private bool
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the public key (hex) for reviewers to verify sessions",
+		Run: func(cmd *cobra.Command, args []string) {
+			var data []byte
+			if private {
+				priv, err := session.LoadPrivateKey()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error loading private key: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Fprintln(os.Stderr, "[!] Exporting the PRIVATE key. Keep the output secret.")
+				data = priv
+			} else {
+				pub, err := session.LoadPublicKey()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error loading public key: %v\n", err)
+					os.Exit(1)
+				}
+				data = pub
+			}
+
+			encoded := hex.EncodeToString(data) + "\n"
+			if output == "" {
+				fmt.Print(encoded)
+				return
+			}
+			if err := os.WriteFile(output, []byte(encoded), 0600); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", output, err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "[*] Key written to %s\n", output)
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the key to a file instead of stdout")
+	cmd.Flags().BoolVar(&private, "private", false, "Export the private key instead (for moving to another workstation)")
+	return cmd
+}
+
+// ─── import ───────────────────────────────────────────────────────────────────
+
+func keysImportCmd() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import a public or private key exported with 'keys export'",
+		Long: `Import a hex-encoded or raw Ed25519 key. A public key alone sets up a
+verify-only keyring; a private key restores full signing capability.
+An existing key is only replaced with --force, which also removes the old
+private key when a lone public key is imported.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			pub, priv, err := session.ParseKey(data)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			dropsPrivate := priv == nil && force && session.KeysExist()
+			if err := session.SaveKeys(pub, priv, force); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if dropsPrivate {
+				fmt.Fprintln(os.Stderr, "[!] Removed the previous private key; this keyring is now verify-only")
+			}
+			fmt.Printf("[*] Imported %s key %s\n", keyKind(priv != nil), session.Fingerprint(pub))
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing keypair")
+	return cmd
+}
+
+// ─── fingerprint ──────────────────────────────────────────────────────────────
+
+func keysFingerprintCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "fingerprint [file]",
+		Short: "Print the fingerprint of the local public key or of a key file",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 1 {
+				data, err := os.ReadFile(args[0])
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				pub, _, err := session.ParseKey(data)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Println(session.Fingerprint(pub))
+				return
+			}
+			pub, err := session.LoadPublicKey()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading public key: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(session.Fingerprint(pub))
+		},
+	}
+}
+
+func keyKind(private bool) string {
+	if private {
+		return "private"
+	}
+	return "public"
+}
@@ -7,13 +7,17 @@ import (
 
 	"coldcase/pkg/binwalk"
 	"coldcase/pkg/carving"
+	"coldcase/pkg/config"
 	"coldcase/pkg/didier"
 	"coldcase/pkg/exiftool"
 	"coldcase/pkg/hashing"
 	"coldcase/pkg/malware"
+	"coldcase/pkg/manifest"
 	"coldcase/pkg/mobile"
 	"coldcase/pkg/network"
+	"coldcase/pkg/registry"
 	"coldcase/pkg/runner"
+	"coldcase/pkg/session"
 	"coldcase/pkg/sleuthkit"
 	"coldcase/pkg/steg"
 	"coldcase/pkg/sysutils"
@@ -24,8 +28,6 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const defaultSuitePath = "./DidierStevensSuite"
-
 var rootCmd = &cobra.Command{
 	Use:   "coldcase",
 	Short: "Integrated Digital Forensics Tool",
@@ -33,6 +35,15 @@ var rootCmd = &cobra.Command{
 }
 
 func main() {
+	// Global flags given before the command name are applied before the tool
+	// registry is built, so --profile and --base-dir also select the tools.d
+	// directory. Errors are reported again by PersistentPreRun.
+	takeGlobalFlags(os.Args[1:])
+	applyGlobalFlags()
+
+	toolRegistry = buildRegistry()
+	addToolCommands(toolRegistry)
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -40,146 +51,213 @@ func main() {
 }
 
 func init() {
-	// Original tool categories
-	addDidierStevensCommands()
-	addExifToolCommand()
-	addBinwalkCommand()
-	addSleuthKitCommands()
-	addVolatility3Commands()
-
-	// New tool categories
-	addGenericCommands("network", network.Tools())
-	addGenericCommands("carving", carving.Tools())
-	addGenericCommands("malware", malware.Tools())
-	addGenericCommands("hashing", hashing.Tools())
-	addPlasoCommandGroup()
-	addGenericCommands("mobile", mobile.Tools())
-	addGenericCommands("windows-artifacts", wintools.Tools())
-	addGenericCommands("steg", steg.Tools())
-	addGenericCommands("sysutils", sysutils.Tools())
+	rootCmd.PersistentFlags().Duration("timeout", 0, "Abort each tool run after this long (e.g. 30m, 2h); 0 disables")
+	rootCmd.PersistentFlags().String("profile", "", "Configuration profile from ~/.coldcase/config.yaml (env COLDCASE_PROFILE)")
+	for _, k := range config.Keys {
+		rootCmd.PersistentFlags().String(k.Flag, "", fmt.Sprintf("%s (env %s)", k.Help, k.Env))
+	}
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if err := applyGlobalFlags(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	// Built-in utilities
 	addListCommand()
 	addCheckCommand()
 }
 
-// tool is a minimal interface matched by all pkg tool types.
-type tool interface {
-	Name() string
-	Description() string
-	Run(args []string) error
+// activeConfig is the configuration resolved by the last applyGlobalFlags.
+var activeConfig *config.Config
+
+// applyGlobalFlags resolves the configuration (flag > env > profile >
+// default) and copies it into the packages it configures.
+func applyGlobalFlags() error {
+	flags := rootCmd.PersistentFlags()
+	runner.DefaultTimeout, _ = flags.GetDuration("timeout")
+
+	profile, _ := flags.GetString("profile")
+	set := map[string]string{}
+	for _, k := range config.Keys {
+		if v, _ := flags.GetString(k.Flag); v != "" {
+			set[k.Name] = v
+		}
+	}
+	cfg, err := config.Resolve(profile, set)
+	if err != nil {
+		return err
+	}
+	activeConfig = cfg
+
+	session.SetBaseDir(cfg.Get(config.BaseDir))
+	didier.SuitePath = cfg.Get(config.SuitePath)
+	vol3.VolDir = cfg.Get(config.VolatilityDir)
+	runner.Image = cfg.Get(config.Image)
+	runner.Runtime = cfg.Get(config.Runtime)
+	if runner.DefaultMode, err = runner.ParseMode(cfg.Get(config.RunMode)); err != nil {
+		return fmt.Errorf("%s: %w", config.RunMode, err)
+	}
+	if runner.NativeSandbox, err = runner.ParseSandbox(cfg.Get(config.Sandbox)); err != nil {
+		return fmt.Errorf("%s: %w", config.Sandbox, err)
+	}
+	if runner.Backend, err = runner.ParseBackend(cfg.Get(config.Executor)); err != nil {
+		return fmt.Errorf("%s: %w", config.Executor, err)
+	}
+	runner.Remote = runner.RemoteConfig{
+		Host:       cfg.Get(config.RemoteHost),
+		Identity:   cfg.Get(config.RemoteID),
+		KnownHosts: cfg.Get(config.RemoteKnown),
+	}
+	if runner.Remote.Paths, err = runner.ParsePathMaps(cfg.Get(config.RemotePaths)); err != nil {
+		return fmt.Errorf("%s: %w", config.RemotePaths, err)
+	}
+	if runner.EvidenceDrift, err = runner.ParseDriftPolicy(cfg.Get(config.EvidenceDrift)); err != nil {
+		return fmt.Errorf("%s: %w", config.EvidenceDrift, err)
+	}
+	for tool, mode := range cfg.ToolModes() {
+		if _, err := runner.ParseMode(mode); err != nil {
+			return fmt.Errorf("%s%s: %w", config.ToolKeyPrefix, tool, err)
+		}
+	}
+	return nil
 }
 
-// addGenericCommands registers a slice of tools that satisfy the tool interface.
-func addGenericCommands[T tool](_ string, tools []T) {
-	for _, t := range tools {
-		t := t // capture
-		cmd := &cobra.Command{
-			Use:   t.Name(),
-			Short: t.Description(),
-			Run: func(cmd *cobra.Command, args []string) {
-				if err := t.Run(args); err != nil {
-					fmt.Printf("Error running %s: %v\n", t.Name(), err)
-					os.Exit(1)
-				}
-			},
+// toolMode returns the run-mode policy for a registry entry: the configured
+// run_mode.<tool> override, else the entry's own policy.
+func toolMode(e registry.Entry) runner.Mode {
+	key := strings.ReplaceAll(e.Command(), " ", ".")
+	if activeConfig != nil {
+		if m := activeConfig.ToolMode(key); m != "" {
+			return runner.Mode(m)
 		}
-		rootCmd.AddCommand(cmd)
 	}
+	return runner.Mode(e.Mode)
 }
 
-// ─── DidierStevens ────────────────────────────────────────────────────────────
+// ─── Tool registry ────────────────────────────────────────────────────────────
+
+// toolRegistry is the single catalogue the command tree, `list`, `check` and
+// `deps check` are generated from. It holds the compiled-in tools plus any
+// manifests found in the tools.d directories. main builds it once the
+// configuration is known.
+var toolRegistry *registry.Registry
+
+func buildRegistry() *registry.Registry {
+	r := registry.New()
+	didier.Register(r)
+	network.Register(r)
+	carving.Register(r)
+	malware.Register(r)
+	hashing.Register(r)
+	timeline.Register(r)
+	mobile.Register(r)
+	wintools.Register(r)
+	steg.Register(r)
+	sysutils.Register(r)
+	sleuthkit.Register(r)
+	vol3.Register(r)
+	exiftool.Register(r)
+	binwalk.Register(r)
+
+	// Declarative tools from tools.d, after the built-ins so they cannot shadow
+	// them or the CLI's own commands.
+	ms, errs := manifest.Load(manifest.Dirs()...)
+	errs = append(errs, manifest.Register(r, ms, builtinCommands())...)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "[!] Skipping tool manifest: %v\n", err)
+	}
+	return r
+}
 
-func addDidierStevensCommands() {
-	for _, t := range didier.Tools(defaultSuitePath) {
-		t := t
-		cmd := &cobra.Command{
-			Use:   t.Name(),
-			Short: t.Description(),
-			Run: func(cmd *cobra.Command, args []string) {
-				if err := t.Run(args); err != nil {
-					fmt.Printf("Error running %s: %v\n", t.Name(), err)
-					os.Exit(1)
-				}
-			},
-		}
-		rootCmd.AddCommand(cmd)
+// builtinCommands returns the names and aliases of the commands registered
+// on rootCmd so far, plus the help and completion commands cobra adds when
+// it runs. It is called from main, once every init has added its command.
+func builtinCommands() []string {
+	names := []string{"help", "completion"}
+	for _, c := range rootCmd.Commands() {
+		names = append(names, c.Name())
+		names = append(names, c.Aliases...)
 	}
+	return names
 }
 
-// ─── ExifTool ─────────────────────────────────────────────────────────────────
+// groupShort describes the parent commands that registry groups create.
+var groupShort = map[string]string{
+	"plaso": "Plaso (log2timeline) supertimeline generation and analysis",
+}
 
-func addExifToolCommand() {
-	t := exiftool.New()
-	rootCmd.AddCommand(&cobra.Command{
-		Use:   t.Name(),
-		Short: t.Description(),
-		Run: func(cmd *cobra.Command, args []string) {
-			if err := t.Run(args); err != nil {
-				fmt.Printf("Error running exiftool: %v\n", err)
-				os.Exit(1)
+// addToolCommands registers one cobra command per registry entry, creating
+// group parents (e.g. "plaso") on first use.
+func addToolCommands(r *registry.Registry) {
+	groups := map[string]*cobra.Command{}
+	for _, e := range r.Entries() {
+		parent := rootCmd
+		if e.Group != "" {
+			if groups[e.Group] == nil {
+				groups[e.Group] = &cobra.Command{Use: e.Group, Short: groupShort[e.Group]}
+				rootCmd.AddCommand(groups[e.Group])
 			}
-		},
-	})
+			parent = groups[e.Group]
+		}
+		parent.AddCommand(toolCommand(e))
+	}
 }
 
-// ─── Binwalk ──────────────────────────────────────────────────────────────────
-
-func addBinwalkCommand() {
-	t := binwalk.New()
-	rootCmd.AddCommand(&cobra.Command{
-		Use:   t.Name(),
-		Short: t.Description(),
+// toolCommand builds the cobra command for a registry entry. Flag parsing is
+// disabled so every argument after the tool name, including the tool's own
+// -h/--help, reaches the wrapped binary unchanged.
+func toolCommand(e registry.Entry) *cobra.Command {
+	return &cobra.Command{
+		Use:                e.Name + " [args...]",
+		Short:              e.Description,
+		DisableFlagParsing: true,
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := t.Run(args); err != nil {
-				fmt.Printf("Error running binwalk: %v\n", err)
+			args, err := takeGlobalFlags(args)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := applyGlobalFlags(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			runner.ToolMode = toolMode(e)
+			if err := e.Tool.Run(args); err != nil {
+				fmt.Printf("Error running %s: %v\n", e.Command(), err)
 				os.Exit(1)
 			}
 		},
-	})
-}
-
-// ─── Sleuth Kit ───────────────────────────────────────────────────────────────
-
-func addSleuthKitCommands() {
-	for _, t := range sleuthkit.Tools() {
-		t := t
-		rootCmd.AddCommand(&cobra.Command{
-			Use:   t.Name(),
-			Short: t.Description(),
-			Run: func(cmd *cobra.Command, args []string) {
-				if err := t.Run(args); err != nil {
-					fmt.Printf("Error running %s: %v\n", t.Name(), err)
-					os.Exit(1)
-				}
-			},
-		})
 	}
 }
 
-// ─── Volatility3 ──────────────────────────────────────────────────────────────
-
-func addVolatility3Commands() {
-	for _, t := range vol3.Tools() {
-		t := t
-		cmd := &cobra.Command{
-			Use:   t.Name(),
-			Short: t.Description(),
-			Long:  t.Description() + " — Volatility3 memory forensics",
-			Run: func(cmd *cobra.Command, args []string) {
-				if err := t.Run(args); err != nil {
-					fmt.Printf("Error running %s: %v\n", t.Name(), err)
-					os.Exit(1)
-				}
-			},
+// takeGlobalFlags consumes ColdCase's persistent flags given before the tool
+// name (e.g. "coldcase --timeout 1h tshark -r x.pcap"), which cobra leaves in
+// the argument list when flag parsing is disabled.
+func takeGlobalFlags(args []string) ([]string, error) {
+	flags := rootCmd.PersistentFlags()
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		name, value, hasValue := strings.Cut(args[0][2:], "=")
+		f := flags.Lookup(name)
+		if f == nil {
+			break
+		}
+		args = args[1:]
+		if !hasValue {
+			switch {
+			case f.NoOptDefVal != "":
+				value = f.NoOptDefVal
+			case len(args) == 0:
+				return nil, fmt.Errorf("flag needs an argument: --%s", name)
+			default:
+				value, args = args[0], args[1:]
+			}
 		}
-		if strings.HasPrefix(t.Name(), "windows.") ||
-			strings.HasPrefix(t.Name(), "linux.") ||
-			strings.HasPrefix(t.Name(), "mac.") {
-			cmd.Flags().StringP("file", "f", "", "Memory image file to analyze")
+		if err := flags.Set(name, value); err != nil {
+			return nil, err
 		}
-		rootCmd.AddCommand(cmd)
 	}
+	return args, nil
 }
 
 // ─── Built-in utilities ───────────────────────────────────────────────────────
@@ -199,96 +277,3 @@ func addCheckCommand() {
 		Run:   checkTools,
 	})
 }
-
-// ─── Plaso ──────────────────────────────────────────────────────────────────
-
-func addPlasoCommandGroup() {
-	plasoCmd := &cobra.Command{
-		Use:   "plaso",
-		Short: "Plaso (log2timeline) supertimeline generation and analysis",
-	}
-
-	for _, t := range timeline.Tools() {
-		t := t
-		var cmd *cobra.Command
-
-		switch t.Name() {
-		case "log2timeline":
-			cmd = &cobra.Command{
-				Use:   "parse",
-				Short: t.Description(),
-				Run: func(cmd *cobra.Command, args []string) {
-					if err := t.Run(args); err != nil {
-						fmt.Printf("Error running plaso parse: %v\n", err)
-						os.Exit(1)
-					}
-				},
-			}
-		case "psort":
-			cmd = &cobra.Command{
-				Use:   "sort",
-				Short: t.Description(),
-				Run: func(cmd *cobra.Command, args []string) {
-					if err := t.Run(args); err != nil {
-						fmt.Printf("Error running plaso sort: %v\n", err)
-						os.Exit(1)
-					}
-				},
-			}
-		case "psteal":
-			cmd = &cobra.Command{
-				Use:   "psteal",
-				Short: t.Description(),
-				Run: func(cmd *cobra.Command, args []string) {
-					if err := t.Run(args); err != nil {
-						fmt.Printf("Error running plaso psteal: %v\n", err)
-						os.Exit(1)
-					}
-				},
-			}
-		case "hayabusa", "evtx_dump", "timeliner", "chainsaw":
-			// Keep these as top-level if needed, or skip for now.
-			// Actually, the user specifically mentioned plaso parse/parsers.
-			// Let's add the other timeline tools back as top-level or in another group.
-			rootCmd.AddCommand(&cobra.Command{
-				Use:   t.Name(),
-				Short: t.Description(),
-				Run: func(cmd *cobra.Command, args []string) {
-					if err := t.Run(args); err != nil {
-						fmt.Printf("Error running %s: %v\n", t.Name(), err)
-						os.Exit(1)
-					}
-				},
-			})
-			continue
-		}
-
-		if cmd != nil {
-			plasoCmd.AddCommand(cmd)
-		}
-	}
-
-	// Add the specific "parsers --list" subcommand
-	plasoCmd.AddCommand(&cobra.Command{
-		Use:   "parsers",
-		Short: "List all available plaso parsers",
-		Run: func(cmd *cobra.Command, args []string) {
-			// Find log2timeline tool to run it with --parsers list
-			for _, t := range timeline.Tools() {
-				if t.Name() == "log2timeline" {
-					if err := t.Run([]string{"--parsers", "list"}); err != nil {
-						fmt.Printf("Error listing plaso parsers: %v\n", err)
-						os.Exit(1)
-					}
-					return
-				}
-			}
-		},
-	})
-
-	rootCmd.AddCommand(plasoCmd)
-}
-
-// ─── runner re-export for container.go ───────────────────────────────────────
-// This allows container.go (same package) to use runner without its own import.
-var _ = runner.ContainerAvailable
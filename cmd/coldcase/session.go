@@ -0,0 +1,523 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+
+	"coldcase/pkg/runner"
+	"coldcase/pkg/session"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// passphraseFile is the --passphrase-file flag shared by all session commands.
+var passphraseFile string
+
+func init() {
+	sessionCmd := &cobra.Command{
+		Use:   "session",
+		Short: "Manage forensic audit sessions",
+		Long: `Start, inspect, lock, seal, verify and export forensic sessions.
+While COLDCASE_SESSION_ID is set, every tool run is hashed and logged to that session.`,
+	}
+
+	sessionCmd.AddCommand(
+		sessionStartCmd(),
+		sessionListCmd(),
+		sessionShowCmd(),
+		sessionStateCmd("lock", session.StateLocked, "Lock a session so no further commands are logged"),
+		sessionStateCmd("unlock", session.StateUnlocked, "Unlock a locked session"),
+		sessionStateCmd("seal", session.StateSealed, "Permanently seal a session (irreversible)"),
+		sessionVerifyCmd(),
+		sessionRepairCmd(),
+		sessionNoteCmd(),
+		sessionOutputCmd(),
+		sessionExportCmd(),
+	)
+	sessionCmd.PersistentFlags().StringVar(&passphraseFile, "passphrase-file", "",
+		"Read the encrypted-session passphrase from this file (default: $"+session.EnvPassphrase+" or prompt)")
+
+	rootCmd.AddCommand(sessionCmd)
+}
+
+// ─── start ────────────────────────────────────────────────────────────────────
+
+func sessionStartCmd() *cobra.Command {
+	var investigator, email string
+	var opts session.CreateOpts
+	cmd := &cobra.Command{
+		Use:   "start <id>",
+		Short: "Start a new session and print the export line for your shell",
+		Long: `Create a new forensic session. The command prints an export statement
+on stdout so it can be activated in the current shell with:
+  eval $(coldcase session start case-001 --sign)`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			id := args[0]
+			if err := validateSessionID(id); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if investigator == "" {
+				investigator = currentUsername()
+			}
+
+			if _, err := runner.ParseMode(opts.RunMode); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			m := newSessionManager(true)
+			s, err := m.Create(id, investigator, email, opts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Status goes to stderr so stdout stays eval-able.
+			fmt.Fprintf(os.Stderr, "[*] Session '%s' started (signed: %v, encrypted: %v)\n", s.ID, s.Signed, s.Encrypted)
+			if s.Encrypted {
+				fmt.Fprintf(os.Stderr, "[!] Set %s in this shell so tool runs can write encrypted outputs.\n", session.EnvPassphrase)
+			}
+			fmt.Printf("export COLDCASE_SESSION_ID=%s\n", s.ID)
+		},
+	}
+	cmd.Flags().StringVar(&investigator, "investigator", "", "Investigator name (default: current user)")
+	cmd.Flags().StringVar(&email, "email", "", "Investigator email address")
+	cmd.Flags().BoolVar(&opts.Sign, "sign", false, "Sign every logged command with the investigator key")
+	cmd.Flags().BoolVar(&opts.Encrypt, "encrypt", false, "Encrypt saved tool outputs with a passphrase-derived key")
+	cmd.Flags().BoolVar(&opts.EncryptLog, "encrypt-log", false, "Also encrypt session.json (implies --encrypt)")
+	cmd.Flags().StringVar(&opts.RunMode, "run-mode", "", "Run-mode policy every command in this session must follow, overriding tool and global settings (e.g. container-only)")
+	return cmd
+}
+
+// ─── list ─────────────────────────────────────────────────────────────────────
+
+func sessionListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List all sessions",
+		Run: func(cmd *cobra.Command, args []string) {
+			m := session.NewManager()
+			ids, err := m.List()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if len(ids) == 0 {
+				fmt.Println("No sessions found.")
+				return
+			}
+			active := session.GetActiveSessionID()
+			for _, id := range ids {
+				s, err := m.Load(id)
+				if err != nil {
+					fmt.Printf("  %-24s [unreadable: %v]\n", id, err)
+					continue
+				}
+				marker := " "
+				if id == active {
+					marker = "*"
+				}
+				fmt.Printf("%s %-24s %-9s %4d commands  %s\n", marker, s.ID, s.State, len(s.Commands), s.Created.Format("2006-01-02 15:04"))
+			}
+		},
+	}
+}
+
+// ─── show ─────────────────────────────────────────────────────────────────────
+
+func sessionShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show [id]",
+		Short: "Show session details (default: active session)",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			_, s := mustLoadSession(args)
+			fmt.Printf("Session      : %s\n", s.ID)
+			fmt.Printf("Investigator : %s", s.Investigator)
+			if s.Email != "" {
+				fmt.Printf(" <%s>", s.Email)
+			}
+			fmt.Println()
+			fmt.Printf("Created      : %s\n", s.Created.Format("2006-01-02 15:04:05"))
+			fmt.Printf("State        : %s\n", s.State)
+			if s.SealedAt != nil {
+				fmt.Printf("Sealed       : %s\n", s.SealedAt.Format("2006-01-02 15:04:05"))
+			}
+			fmt.Printf("Signed       : %v\n", s.Signed)
+			fmt.Printf("Encrypted    : %v\n", s.Encrypted)
+			if s.RunMode != "" {
+				fmt.Printf("Run mode     : %s\n", s.RunMode)
+			}
+			images := make([]string, 0, len(s.ImagePins))
+			for image := range s.ImagePins {
+				images = append(images, image)
+			}
+			sort.Strings(images)
+			for _, image := range images {
+				fmt.Printf("Image        : %s -> %s\n", image, s.ImagePins[image].Ref)
+			}
+			fmt.Printf("Commands     : %d\n", len(s.Commands))
+			fmt.Printf("Evidence     : %d\n", len(s.Evidence))
+			if len(s.Notes) > 0 {
+				fmt.Printf("Notes        : %d\n", len(s.Notes))
+			}
+
+			for _, c := range s.Commands {
+				fmt.Printf("  [%03d] %s  %s\n", c.Index, c.Timestamp.Format("2006-01-02 15:04:05"), c.FullCommand)
+			}
+			for _, n := range s.Notes {
+				fmt.Printf("  note  %s  %s: %s\n", n.Time.Format("2006-01-02 15:04:05"), n.Author, n.Text)
+			}
+		},
+	}
+}
+
+// ─── lock / unlock / seal ─────────────────────────────────────────────────────
+
+func sessionStateCmd(use string, to session.State, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " [id]",
+		Short: short,
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			m, s := mustLoadSession(args)
+			if err := m.SetState(s, to); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("[*] Session '%s' is now %s\n", s.ID, s.State)
+		},
+	}
+}
+
+// ─── verify ───────────────────────────────────────────────────────────────────
+
+func sessionVerifyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify [id]",
+		Short: "Verify the command hash chain and signatures",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			m, s := mustLoadSession(args)
+			var pub ed25519.PublicKey
+			if s.Signed {
+				var err error
+				pub, err = session.LoadPublicKey()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error loading public key: %v\n", err)
+					os.Exit(1)
+				}
+			}
+			if err := m.VerifySession(s, pub); err != nil {
+				fmt.Printf("[x] Verification FAILED: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("[*] Hash chain intact across %d commands\n", len(s.Commands))
+			if s.Journal != nil {
+				fmt.Printf("[*] Journal intact across %d events and matches session.json\n", s.Journal.Seq)
+			}
+			if s.Signed {
+				fmt.Printf("[*] All signatures verified against %s\n", s.PublicKey)
+			} else {
+				fmt.Println("[!] Session is not signed; the chain detects edits but not a rewritten log.")
+			}
+			for _, c := range session.ImageChanges(s) {
+				fmt.Printf("[!] Command #%d ran %s at %s, not the session's %s\n", c.Index, c.Image, c.To, c.From)
+			}
+
+			if s.State != session.StateSealed {
+				return
+			}
+			report, err := m.VerifySeal(s, pub)
+			if err != nil {
+				fmt.Printf("[x] Seal verification FAILED: %v\n", err)
+				os.Exit(1)
+			}
+			for _, p := range report.Modified {
+				fmt.Printf("[x] Modified since seal : %s\n", p)
+			}
+			for _, p := range report.Missing {
+				fmt.Printf("[x] Deleted since seal  : %s\n", p)
+			}
+			for _, p := range report.Added {
+				fmt.Printf("[x] Added since seal    : %s\n", p)
+			}
+			if !report.RootMatches {
+				fmt.Printf("[x] Merkle root mismatch: sealed %s\n", s.MerkleRoot)
+			}
+			if !report.Intact() {
+				os.Exit(1)
+			}
+			fmt.Printf("[*] Seal intact: Merkle root %s over %d outputs\n", s.MerkleRoot, len(s.SealedOutputs))
+		},
+	}
+}
+
+// ─── repair ───────────────────────────────────────────────────────────────────
+
+func sessionRepairCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "repair [id]",
+		Short: "Rebuild session.json from the session journal",
+		Long: `Rebuild the session.json snapshot by replaying journal.jsonl, the
+append-only record of every session event. An incomplete final journal line
+left by a crash is removed first. A journal that fails verification anywhere
+else is reported and left untouched.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			id := session.GetActiveSessionID()
+			if len(args) > 0 {
+				id = args[0]
+			}
+			if err := validateSessionID(id); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			report, err := newSessionManager(false).Repair(id)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if report.TornBytes > 0 {
+				fmt.Printf("[!] Removed an incomplete final journal line (%d bytes)\n", report.TornBytes)
+			}
+			fmt.Printf("[*] Rebuilt session.json for '%s' from %d journal events\n", id, report.Events)
+		},
+	}
+}
+
+// ─── note ─────────────────────────────────────────────────────────────────────
+
+func sessionNoteCmd() *cobra.Command {
+	var id string
+	cmd := &cobra.Command{
+		Use:   "note <text>",
+		Short: "Add a note to a session (default: active session)",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var ids []string
+			if id != "" {
+				ids = []string{id}
+			}
+			m, s := mustLoadSession(ids)
+			if err := m.AddNote(s, currentUsername(), args[0]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("[*] Note added to session '%s'\n", s.ID)
+		},
+	}
+	cmd.Flags().StringVar(&id, "session", "", "Session ID (default: $COLDCASE_SESSION_ID)")
+	return cmd
+}
+
+// ─── output ───────────────────────────────────────────────────────────────────
+
+func sessionOutputCmd() *cobra.Command {
+	var id, stream string
+	cmd := &cobra.Command{
+		Use:   "output <n>",
+		Short: "Print the saved output of command #n, decrypting it if needed",
+		Long: `Print the saved stdout, stderr or parsed output of command #n of a session
+(default: active session). Encrypted outputs are decrypted chunk by chunk
+as they are printed, so outputs of any size can be read.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			idx, err := strconv.Atoi(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid command number %q\n", args[0])
+				os.Exit(1)
+			}
+			var ids []string
+			if id != "" {
+				ids = []string{id}
+			}
+			m, s := mustLoadSession(ids)
+			if idx < 1 || idx > len(s.Commands) {
+				fmt.Fprintf(os.Stderr, "Error: session '%s' has no command #%d\n", s.ID, idx)
+				os.Exit(1)
+			}
+			c := s.Commands[idx-1]
+			var rel string
+			switch stream {
+			case "stdout":
+				rel = c.OutputFile
+			case "stderr":
+				rel = c.ErrorFile
+			case "parsed":
+				rel = c.ParsedFile
+			default:
+				fmt.Fprintf(os.Stderr, "Error: unknown stream %q (use stdout, stderr or parsed)\n", stream)
+				os.Exit(1)
+			}
+			if rel == "" {
+				fmt.Fprintf(os.Stderr, "Error: command #%d has no saved %s\n", idx, stream)
+				os.Exit(1)
+			}
+
+			r, err := m.OpenOutput(s, rel)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer r.Close()
+			if _, err := io.Copy(os.Stdout, r); err != nil {
+				fmt.Fprintf(os.Stderr, "\nError: %s: %v\n", rel, err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&id, "session", "", "Session ID (default: $COLDCASE_SESSION_ID)")
+	cmd.Flags().StringVar(&stream, "stream", "stdout", "Output to print: stdout, stderr or parsed")
+	return cmd
+}
+
+// ─── export ───────────────────────────────────────────────────────────────────
+
+func sessionExportCmd() *cobra.Command {
+	var format, output string
+	cmd := &cobra.Command{
+		Use:   "export [id]",
+		Short: "Export a session report as JSON, Markdown or HTML",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			m, s := mustLoadSession(args)
+			if err := m.LoadPreviews(s); err != nil {
+				fmt.Fprintf(os.Stderr, "Error decrypting outputs: %v\n", err)
+				os.Exit(1)
+			}
+
+			var report string
+			var err error
+			switch strings.ToLower(format) {
+			case "json":
+				report, err = session.ExportJSON(s)
+			case "md", "markdown":
+				report, err = session.ExportMarkdown(s)
+			case "html":
+				report, err = session.ExportHTML(s)
+			default:
+				fmt.Fprintf(os.Stderr, "Error: unknown format %q (use json, md or html)\n", format)
+				os.Exit(1)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+				os.Exit(1)
+			}
+
+			if output == "" {
+				fmt.Print(report)
+				return
+			}
+			if err := os.WriteFile(output, []byte(report), 0600); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", output, err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "[*] Report written to %s\n", output)
+		},
+	}
+	cmd.Flags().StringVarP(&format, "format", "F", "json", "Report format: json, md or html")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the report to a file instead of stdout")
+	return cmd
+}
+
+// ─── helpers ──────────────────────────────────────────────────────────────────
+
+// mustLoadSession loads the session named in args, or the active session when
+// no ID is given, along with the manager that decrypted it. It exits the
+// process on failure.
+func mustLoadSession(args []string) (*session.Manager, *session.Session) {
+	id := session.GetActiveSessionID()
+	if len(args) > 0 {
+		id = args[0]
+	}
+	if id == "" {
+		fmt.Fprintln(os.Stderr, "Error: no session ID given and COLDCASE_SESSION_ID is not set")
+		os.Exit(1)
+	}
+	if err := validateSessionID(id); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	m := newSessionManager(false)
+	s, err := m.Load(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading session '%s': %v\n", id, err)
+		os.Exit(1)
+	}
+	return m, s
+}
+
+// newSessionManager returns a manager that asks for encrypted-session
+// passphrases from --passphrase-file, the environment, or the terminal, in
+// that order. confirm makes an interactive prompt ask twice.
+func newSessionManager(confirm bool) *session.Manager {
+	m := session.NewManager()
+	m.SetPassphraseFunc(func(id string) (string, error) {
+		if passphraseFile != "" {
+			data, err := os.ReadFile(passphraseFile)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimRight(string(data), "\r\n"), nil
+		}
+		if p := os.Getenv(session.EnvPassphrase); p != "" {
+			return p, nil
+		}
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return "", fmt.Errorf("encrypted session '%s' requires a passphrase (use --passphrase-file or set %s)", id, session.EnvPassphrase)
+		}
+		p, err := readPassphrase(fmt.Sprintf("Passphrase for session '%s': ", id))
+		if err != nil || !confirm {
+			return p, err
+		}
+		again, err := readPassphrase("Confirm passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != p {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+		return p, nil
+	})
+	return m
+}
+
+func readPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	data, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return string(data), err
+}
+
+// validateSessionID rejects IDs that would escape the sessions directory or
+// break the shell export line.
+func validateSessionID(id string) error {
+	if id == "" || id == "." || id == ".." {
+		return fmt.Errorf("invalid session ID %q", id)
+	}
+	for _, r := range id {
+		ok := r == '-' || r == '_' || r == '.' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if !ok {
+			return fmt.Errorf("invalid session ID %q: use letters, digits, '.', '-' or '_'", id)
+		}
+	}
+	return nil
+}
+
+func currentUsername() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -1,7 +1,10 @@
 // Package binwalk integrates the Binwalk firmware analysis tool.
 package binwalk
 
-import "coldcase/pkg/runner"
+import (
+	"coldcase/pkg/registry"
+	"coldcase/pkg/runner"
+)
 
 // BinwalkTool wraps the binwalk binary.
 type BinwalkTool struct{}
@@ -24,3 +27,14 @@ func (b *BinwalkTool) Run(args []string) error {
 		Args:   args,
 	})
 }
+
+// Register adds the binwalk command to r.
+func Register(r *registry.Registry) {
+	t := New()
+	r.Add(registry.Entry{
+		Name:        t.Name(),
+		Category:    "General Tools",
+		Description: t.Description(),
+		Tool:        t,
+	})
+}
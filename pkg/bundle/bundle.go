@@ -0,0 +1,278 @@
+// Package bundle moves the ColdCase container image between machines that
+// have no registry access. A bundle is a tar file holding a signed
+// manifest.json followed by the image tarball produced by `docker save`.
+package bundle
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"coldcase/pkg/runner"
+	"coldcase/pkg/session"
+)
+
+// Format is the bundle layout version written by Export.
+const Format = 1
+
+const (
+	manifestName = "manifest.json"
+	imageName    = "image.tar"
+)
+
+// versionProbeTimeout bounds the single container run that collects tool versions.
+const versionProbeTimeout = 5 * time.Minute
+
+// Manifest describes the image inside a bundle. The signature covers every
+// other field, so the tarball hash binds the image content to the signer.
+type Manifest struct {
+	Format  int       `json:"format"`
+	Image   string    `json:"image"`    // tag the image is loaded under, e.g. "coldcase:latest"
+	Digest  string    `json:"digest"`   // digest the runner pins the tag to
+	ImageID string    `json:"image_id"` // image ID, which survives save/load
+	Runtime string    `json:"runtime"`
+	Created time.Time `json:"created"`
+	// ToolVersions holds the first line of `<binary> --version` for every
+	// registered binary present in the image.
+	ToolVersions  map[string]string `json:"tool_versions"`
+	TarballSHA256 string            `json:"tarball_sha256"`
+	TarballSize   int64             `json:"tarball_size"`
+	Signer        string            `json:"signer"` // fingerprint of the signing key
+	Signature     string            `json:"signature"`
+}
+
+// payload returns the bytes the signature is computed over.
+func (m Manifest) payload() ([]byte, error) {
+	m.Signature = ""
+	return json.Marshal(m)
+}
+
+// Export saves image with runtime and writes a bundle to out, signed with priv.
+// binaries are probed inside the image for their versions.
+func Export(runtime, image, out string, binaries []string, priv ed25519.PrivateKey) (*Manifest, error) {
+	_, digest, err := runner.ResolveImage(runtime, image)
+	if err != nil {
+		return nil, err
+	}
+	id, err := imageID(runtime, image)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(out), ".coldcase-image-*.tar")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	save := exec.Command(runtime, "save", "-o", tmp.Name(), image)
+	save.Stderr = os.Stderr
+	if err := save.Run(); err != nil {
+		return nil, fmt.Errorf("%s save failed: %w", runtime, err)
+	}
+	sum, _, err := session.HashFile(tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{
+		Format:        Format,
+		Image:         image,
+		Digest:        digest,
+		ImageID:       id,
+		Runtime:       runtime,
+		Created:       time.Now().UTC(),
+		ToolVersions:  probeVersions(runtime, image, binaries),
+		TarballSHA256: sum,
+		TarballSize:   info.Size(),
+		Signer:        session.Fingerprint(priv.Public().(ed25519.PublicKey)),
+	}
+	data, err := m.payload()
+	if err != nil {
+		return nil, err
+	}
+	m.Signature = session.Sign(priv, data)
+
+	if err := writeBundle(out, m, tmp.Name()); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Import checks the bundle at path against pub and loads its image with
+// runtime. Nothing is loaded unless the signature, tarball hash and size all
+// match; the loaded image ID is checked against the manifest afterwards.
+func Import(runtime, path string, pub ed25519.PublicKey) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tr := tar.NewReader(f)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestName {
+		return nil, fmt.Errorf("%s is not a ColdCase bundle: %s must come first", path, manifestName)
+	}
+	var m Manifest
+	dec := json.NewDecoder(tr)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	if m.Format != Format {
+		return nil, fmt.Errorf("unsupported bundle format %d (expected %d)", m.Format, Format)
+	}
+	if fp := session.Fingerprint(pub); m.Signer != fp {
+		return nil, fmt.Errorf("bundle was signed by %s, not the trusted key %s", m.Signer, fp)
+	}
+	data, err := m.payload()
+	if err != nil {
+		return nil, err
+	}
+	if !session.Verify(pub, data, m.Signature) {
+		return nil, errors.New("bundle manifest signature is invalid")
+	}
+
+	hdr, err = tr.Next()
+	if err != nil || hdr.Name != imageName {
+		return nil, fmt.Errorf("bundle is missing %s", imageName)
+	}
+	tmp, err := os.CreateTemp("", "coldcase-image-*.tar")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), tr)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot extract image: %w", err)
+	}
+	if n != m.TarballSize {
+		return nil, fmt.Errorf("image tarball is %d bytes, manifest says %d", n, m.TarballSize)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != m.TarballSHA256 {
+		return nil, fmt.Errorf("image tarball SHA-256 %s does not match manifest %s", sum, m.TarballSHA256)
+	}
+
+	load := exec.Command(runtime, "load", "-i", tmp.Name())
+	load.Stdout = os.Stdout
+	load.Stderr = os.Stderr
+	if err := load.Run(); err != nil {
+		return nil, fmt.Errorf("%s load failed: %w", runtime, err)
+	}
+	id, err := imageID(runtime, m.Image)
+	if err != nil {
+		return nil, err
+	}
+	if id != m.ImageID {
+		return nil, fmt.Errorf("loaded image %s has ID %s, manifest says %s", m.Image, id, m.ImageID)
+	}
+	return &m, nil
+}
+
+// ─── internal ─────────────────────────────────────────────────────────────────
+
+// writeBundle writes the manifest and image tarball to out via a temporary
+// file, so an interrupted export never leaves a truncated bundle behind.
+func writeBundle(out string, m *Manifest, imagePath string) error {
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	img, err := os.Open(imagePath)
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+
+	partial := out + ".partial"
+	f, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(partial)
+
+	tw := tar.NewWriter(f)
+	err = tw.WriteHeader(&tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(manifest)), ModTime: m.Created})
+	if err == nil {
+		_, err = tw.Write(manifest)
+	}
+	if err == nil {
+		err = tw.WriteHeader(&tar.Header{Name: imageName, Mode: 0644, Size: m.TarballSize, ModTime: m.Created})
+	}
+	if err == nil {
+		_, err = io.Copy(tw, img)
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(partial, out)
+}
+
+// imageID returns the ID of image, normalised to sha256:<hex>.
+func imageID(runtime, image string) (string, error) {
+	out, err := exec.Command(runtime, "image", "inspect", "--format", "{{.Id}}", image).Output()
+	if err != nil {
+		return "", fmt.Errorf("image %s not found with %s", image, runtime)
+	}
+	id := strings.TrimSpace(string(out))
+	if !strings.HasPrefix(id, "sha256:") {
+		id = "sha256:" + id
+	}
+	return id, nil
+}
+
+// versionScript prints "<binary>\t<first line of --version>" for every
+// argument that exists in the image.
+const versionScript = `for b in "$@"; do
+  command -v "$b" >/dev/null 2>&1 || continue
+  v=$(timeout 10 "$b" --version </dev/null 2>&1 | grep -m1 .)
+  printf '%s\t%s\n' "$b" "$v"
+done`
+
+// probeVersions collects tool versions from image in a single offline
+// container run. Failures leave the map empty rather than failing the export.
+func probeVersions(runtime, image string, binaries []string) map[string]string {
+	versions := map[string]string{}
+	ctx, cancel := context.WithTimeout(context.Background(), versionProbeTimeout)
+	defer cancel()
+
+	args := []string{"run", "--rm", "--network", "none", "--entrypoint", "sh", image, "-c", versionScript, "sh"}
+	out, err := exec.CommandContext(ctx, runtime, append(args, binaries...)...).Output()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[!] Could not probe tool versions in %s: %v\n", image, err)
+		return versions
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if name, v, ok := strings.Cut(line, "\t"); ok {
+			versions[name] = strings.TrimSpace(v)
+		}
+	}
+	return versions
+}
@@ -2,6 +2,7 @@
 package carving
 
 import (
+	"coldcase/pkg/registry"
 	"coldcase/pkg/runner"
 )
 
@@ -20,7 +21,14 @@ func (c *CarvingTool) Run(args []string) error {
 	if bin == "" {
 		bin = c.name
 	}
-	return runner.Run(runner.RunOpts{Binary: bin, Args: args})
+	return runner.Run(runner.RunOpts{Binary: bin, Args: args, Outputs: outputArgs[c.name]})
+}
+
+// outputArgs lists the options through which each tool writes its results.
+var outputArgs = map[string][]runner.OutputArg{
+	"foremost":       {{Flag: "-o", Dir: true}},
+	"scalpel":        {{Flag: "-o", Dir: true}},
+	"bulk-extractor": {{Flag: "-o", Dir: true}},
 }
 
 // Tools returns all carving and recovery tools.
@@ -35,3 +43,16 @@ func Tools() []*CarvingTool {
 		{"safecopy", "Data recovery tool for damaged media", "safecopy"},
 	}
 }
+
+// Register adds the carving and recovery tools to r.
+func Register(r *registry.Registry) {
+	for _, t := range Tools() {
+		r.Add(registry.Entry{
+			Name:        t.name,
+			Binary:      t.bin,
+			Category:    "File Carving & Recovery",
+			Description: t.desc,
+			Tool:        t,
+		})
+	}
+}
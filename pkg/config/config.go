@@ -0,0 +1,356 @@
+// Package config resolves ColdCase settings from command-line flags, the
+// environment, named profiles in ~/.coldcase/config.yaml and built-in
+// defaults, in that order of precedence.
+//
+// Example config.yaml:
+//
+//	profile: lab
+//	settings:
+//	  suite_path: /opt/DidierStevensSuite
+//	profiles:
+//	  lab:
+//	    runtime: docker
+//	  airgapped:
+//	    runtime: podman
+//	    image: registry.local/coldcase:2.1
+//
+// Values under "settings" apply to every profile; a profile overrides them.
+// Relative paths in the file are resolved against the file's directory.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Setting keys.
+const (
+	SuitePath     = "suite_path"
+	VolatilityDir = "volatility_dir"
+	Image         = "image"
+	Runtime       = "runtime"
+	BaseDir       = "base_dir"
+	RunMode       = "run_mode"
+	Sandbox       = "sandbox"
+	Executor      = "executor"
+	RemoteHost    = "remote_host"
+	RemoteID      = "remote_identity"
+	RemoteKnown   = "remote_known_hosts"
+	RemotePaths   = "remote_paths"
+	EvidenceDrift = "evidence_drift"
+)
+
+// ToolKeyPrefix prefixes per-tool run-mode settings, e.g. "run_mode.tshark"
+// or "run_mode.plaso.parse".
+const ToolKeyPrefix = RunMode + "."
+
+// EnvProfile selects the profile when --profile is not given.
+const EnvProfile = "COLDCASE_PROFILE"
+
+// ProfileKey is the pseudo-setting holding the default profile name.
+const ProfileKey = "profile"
+
+// Key describes one setting.
+type Key struct {
+	Name string
+	// Env is the environment variable that overrides the setting.
+	Env string
+	// Flag is the global CLI flag that overrides the setting.
+	Flag string
+	// Path marks settings that hold filesystem paths.
+	Path bool
+	Help string
+	// fallback computes the built-in default.
+	fallback func() string
+}
+
+// Keys lists every setting in display order.
+var Keys = []Key{
+	{SuitePath, "COLDCASE_SUITE_PATH", "suite-path", true, "Directory containing the DidierStevens Suite scripts", func() string { return bundled("DidierStevensSuite") }},
+	{VolatilityDir, "COLDCASE_VOLATILITY_DIR", "volatility-dir", true, "Directory containing Volatility3's vol.py", func() string { return bundled("volatility3") }},
+	{Image, "COLDCASE_IMAGE", "image", false, "Container image for fallback execution", func() string { return "coldcase:latest" }},
+	{Runtime, "COLDCASE_RUNTIME", "runtime", false, "Container runtime (docker or podman; empty auto-detects)", func() string { return "" }},
+	{BaseDir, "COLDCASE_HOME", "base-dir", true, "ColdCase data directory (sessions, keys, tools.d)", defaultBaseDir},
+	{RunMode, "COLDCASE_RUN_MODE", "run-mode", false, "Run-mode policy: native-only, container-only, prefer-native or prefer-container", func() string { return "prefer-native" }},
+	{Sandbox, "COLDCASE_SANDBOX", "sandbox", false, "Native-run sandbox on Linux: off, auto, bwrap or userns", func() string { return "off" }},
+	{Executor, "COLDCASE_EXECUTOR", "executor", false, "Execution backend: local (native/container per run mode), or ssh (remote workstation)", func() string { return "local" }},
+	{RemoteHost, "COLDCASE_REMOTE_HOST", "remote-host", false, "Workstation for the ssh executor: [user@]host[:port]", func() string { return "" }},
+	{RemoteID, "COLDCASE_REMOTE_IDENTITY", "remote-identity", true, "SSH private key for the ssh executor (empty uses ssh-agent and ~/.ssh keys)", func() string { return "" }},
+	{RemoteKnown, "COLDCASE_REMOTE_KNOWN_HOSTS", "remote-known-hosts", true, "known_hosts file verifying the remote host key (empty uses ~/.ssh/known_hosts)", func() string { return "" }},
+	{RemotePaths, "COLDCASE_REMOTE_PATHS", "remote-paths", false, "Local-to-remote evidence path mappings, e.g. /mnt/evidence=/srv/evidence,...", func() string { return "" }},
+	{EvidenceDrift, "COLDCASE_EVIDENCE_DRIFT", "evidence-drift", false, "When registered evidence changed since acquisition: refuse to run or warn", func() string { return "refuse" }},
+}
+
+// Lookup returns the key named name.
+func Lookup(name string) (Key, bool) {
+	for _, k := range Keys {
+		if k.Name == name {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+// validKey reports whether name is a setting or a per-tool run mode.
+func validKey(name string) bool {
+	if _, ok := Lookup(name); ok {
+		return true
+	}
+	return strings.HasPrefix(name, ToolKeyPrefix) && len(name) > len(ToolKeyPrefix)
+}
+
+// File is the on-disk layout of config.yaml.
+type File struct {
+	// Profile is the profile used when neither --profile nor COLDCASE_PROFILE is set.
+	Profile  string                       `yaml:"profile,omitempty"`
+	Settings map[string]string            `yaml:"settings,omitempty"`
+	Profiles map[string]map[string]string `yaml:"profiles,omitempty"`
+}
+
+// Path returns the location of config.yaml. It always lives in ~/.coldcase,
+// even when base_dir points the data directory elsewhere.
+func Path() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".coldcase", "config.yaml")
+}
+
+// ReadFile loads config.yaml; a missing file yields an empty config.
+func ReadFile() (*File, error) {
+	f := &File{}
+	data, err := os.ReadFile(Path())
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("%s: %w", Path(), err)
+	}
+	return f, nil
+}
+
+// validate rejects unknown setting names, which are most likely typos.
+func (f *File) validate() error {
+	check := func(where string, m map[string]string) error {
+		for name := range m {
+			if !validKey(name) {
+				return fmt.Errorf("%s: unknown setting %q in %s", Path(), name, where)
+			}
+		}
+		return nil
+	}
+	if err := check("settings", f.Settings); err != nil {
+		return err
+	}
+	for _, name := range f.ProfileNames() {
+		if err := check("profile "+name, f.Profiles[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save writes f to config.yaml.
+func (f *File) Save() error {
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(Path()), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(Path(), data, 0600)
+}
+
+// Set stores value for key in profile, or in the shared settings when
+// profile is empty. An empty value removes the key. The pseudo-key
+// "profile" sets the default profile, creating it if needed.
+func (f *File) Set(profile, key, value string) error {
+	if key == ProfileKey {
+		f.Profile = value
+		if value != "" {
+			f.ensureProfile(value)
+		}
+		return nil
+	}
+	// Unknown keys may still be removed, to repair a hand-edited file.
+	if !validKey(key) && value != "" {
+		return fmt.Errorf("unknown setting %q (valid: %s, %s, %s<tool>)", key, ProfileKey, strings.Join(Names(), ", "), ToolKeyPrefix)
+	}
+
+	m := f.Settings
+	if profile != "" {
+		m = f.ensureProfile(profile)
+	} else if m == nil {
+		m = map[string]string{}
+		f.Settings = m
+	}
+	if value == "" {
+		delete(m, key)
+	} else {
+		m[key] = value
+	}
+	return nil
+}
+
+func (f *File) ensureProfile(name string) map[string]string {
+	if f.Profiles == nil {
+		f.Profiles = map[string]map[string]string{}
+	}
+	if f.Profiles[name] == nil {
+		f.Profiles[name] = map[string]string{}
+	}
+	return f.Profiles[name]
+}
+
+// Names returns the setting names.
+func Names() []string {
+	names := make([]string, len(Keys))
+	for i, k := range Keys {
+		names[i] = k.Name
+	}
+	return names
+}
+
+// Config is the resolved set of settings.
+type Config struct {
+	// Profile is the active profile, or "" when none is selected.
+	Profile string
+	values  map[string]string
+	sources map[string]string
+	// toolModes maps tool commands to their configured run mode.
+	toolModes map[string]string
+}
+
+// Resolve computes every setting. profile and flags come from the command
+// line; flags maps setting names to values and only holds flags that were set.
+func Resolve(profile string, flags map[string]string) (*Config, error) {
+	f, err := ReadFile()
+	if err != nil {
+		return nil, err
+	}
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+
+	if profile == "" {
+		profile = os.Getenv(EnvProfile)
+	}
+	if profile == "" {
+		profile = f.Profile
+	}
+	prof, ok := f.Profiles[profile]
+	if profile != "" && !ok {
+		return nil, fmt.Errorf("profile %q not found in %s", profile, Path())
+	}
+
+	c := &Config{Profile: profile, values: map[string]string{}, sources: map[string]string{}, toolModes: map[string]string{}}
+	for _, m := range []map[string]string{f.Settings, prof} {
+		for name, val := range m {
+			if tool, ok := strings.CutPrefix(name, ToolKeyPrefix); ok {
+				c.toolModes[tool] = val
+			}
+		}
+	}
+	for _, k := range Keys {
+		var val, src string
+		switch {
+		case flags[k.Name] != "":
+			val, src = flags[k.Name], "flag --"+k.Flag
+		case os.Getenv(k.Env) != "":
+			val, src = os.Getenv(k.Env), "env "+k.Env
+		case prof[k.Name] != "":
+			val, src = fromFile(k, prof[k.Name]), "profile "+profile
+		case f.Settings[k.Name] != "":
+			val, src = fromFile(k, f.Settings[k.Name]), "config"
+		default:
+			val, src = k.fallback(), "default"
+		}
+		if k.Path && val != "" {
+			val = absPath(val)
+		}
+		c.values[k.Name], c.sources[k.Name] = val, src
+	}
+	return c, nil
+}
+
+// Get returns the resolved value of key.
+func (c *Config) Get(key string) string { return c.values[key] }
+
+// Source names where the value of key came from.
+func (c *Config) Source(key string) string { return c.sources[key] }
+
+// ToolMode returns the run mode configured for a tool command, or "".
+func (c *Config) ToolMode(tool string) string { return c.toolModes[tool] }
+
+// ToolModes returns every configured per-tool run mode.
+func (c *Config) ToolModes() map[string]string { return c.toolModes }
+
+// ProfileNames returns the profile names defined in f, sorted.
+func (f *File) ProfileNames() []string {
+	var names []string
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fromFile expands a path value read from config.yaml: "~/" is the home
+// directory and relative paths are relative to the config file.
+func fromFile(k Key, val string) string {
+	if !k.Path {
+		return val
+	}
+	val = expandHome(val)
+	if !filepath.IsAbs(val) {
+		val = filepath.Join(filepath.Dir(Path()), val)
+	}
+	return val
+}
+
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, p[1:])
+	}
+	return p
+}
+
+func absPath(p string) string {
+	p = expandHome(p)
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return p
+}
+
+func defaultBaseDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".coldcase")
+}
+
+// bundled locates a directory shipped alongside ColdCase: the working
+// directory first, then next to the executable and its parent (for bin/).
+// It falls back to the working directory when none exists.
+func bundled(name string) string {
+	candidates := []string{name}
+	if exe, err := os.Executable(); err == nil {
+		if exe, err = filepath.EvalSymlinks(exe); err == nil {
+			dir := filepath.Dir(exe)
+			candidates = append(candidates, filepath.Join(dir, name), filepath.Join(filepath.Dir(dir), name))
+		}
+	}
+	for _, c := range candidates {
+		if info, err := os.Stat(c); err == nil && info.IsDir() {
+			return c
+		}
+	}
+	return name
+}
@@ -1,19 +1,27 @@
 // Package didier integrates the DidierStevens Suite of Python-based
 // forensics tools. Each tool is backed by a .py script found under
-// the suite directory (default: ./DidierStevensSuite).
+// the suite directory (SuitePath unless a tool was built for another one).
 package didier
 
 import (
+	"os"
 	"path/filepath"
 
+	"coldcase/pkg/registry"
 	"coldcase/pkg/runner"
 )
 
+// SuitePath is the suite directory used by tools registered through
+// Register. The CLI sets it from the resolved configuration.
+var SuitePath = "./DidierStevensSuite"
+
 // DidierStevensTool wraps a single DidierStevens Python script.
 type DidierStevensTool struct {
 	name        string
-	scriptPath  string
+	script      string
 	description string
+	// suitePath is fixed by Tools; empty means SuitePath at run time.
+	suitePath string
 }
 
 // Name returns the CLI command name for this tool.
@@ -25,18 +33,27 @@ func (d *DidierStevensTool) Description() string { return d.description }
 // Run executes the Python script with the given arguments.
 // Returns an error if python3 is not installed or the script is missing.
 func (d *DidierStevensTool) Run(args []string) error {
-	cmdArgs := append([]string{d.scriptPath}, args...)
+	scriptPath := d.ScriptPath()
+	cmdArgs := append([]string{scriptPath}, args...)
 	return runner.Run(runner.RunOpts{
-		Binary: "python3",
-		Args:   cmdArgs,
+		Binary:      "python3",
+		Args:        cmdArgs,
+		VersionArgs: []string{scriptPath, "--version"},
 	})
 }
 
 // ScriptPath returns the resolved path to the underlying .py script.
-func (d *DidierStevensTool) ScriptPath() string { return d.scriptPath }
+func (d *DidierStevensTool) ScriptPath() string {
+	dir := d.suitePath
+	if dir == "" {
+		dir = SuitePath
+	}
+	return filepath.Join(dir, d.script)
+}
 
 // Tools builds the full set of DidierStevens tools using suitePath as the
 // directory that contains the .py scripts (e.g. "./DidierStevensSuite").
+// An empty suitePath follows SuitePath.
 func Tools(suitePath string) []*DidierStevensTool {
 	defs := []struct {
 		name, script, desc string
@@ -62,9 +79,32 @@ func Tools(suitePath string) []*DidierStevensTool {
 	for _, d := range defs {
 		result = append(result, &DidierStevensTool{
 			name:        d.name,
-			scriptPath:  filepath.Join(suitePath, d.script),
+			script:      d.script,
 			description: d.desc,
+			suitePath:   suitePath,
 		})
 	}
 	return result
 }
+
+// Register adds the DidierStevens tools to r. They look up their scripts
+// under SuitePath when run, so it may be set after registration.
+func Register(r *registry.Registry) {
+	deps := []registry.Dependency{{
+		Name: "DidierStevensSuite",
+		Check: func() bool {
+			info, err := os.Stat(SuitePath)
+			return err == nil && info.IsDir()
+		},
+	}}
+	for _, t := range Tools("") {
+		r.Add(registry.Entry{
+			Name:        t.name,
+			Binary:      "python3",
+			Category:    "DidierStevens Suite",
+			Description: t.description,
+			Deps:        deps,
+			Tool:        t,
+		})
+	}
+}
@@ -1,7 +1,10 @@
 // Package exiftool integrates ExifTool for metadata extraction.
 package exiftool
 
-import "coldcase/pkg/runner"
+import (
+	"coldcase/pkg/registry"
+	"coldcase/pkg/runner"
+)
 
 // ExifTool wraps the exiftool binary.
 type ExifTool struct{}
@@ -20,7 +23,20 @@ func (e *ExifTool) Description() string {
 // Run invokes exiftool with the provided arguments.
 func (e *ExifTool) Run(args []string) error {
 	return runner.Run(runner.RunOpts{
-		Binary: "exiftool",
-		Args:   args,
+		Binary:      "exiftool",
+		Args:        args,
+		VersionArgs: []string{"-ver"},
+	})
+}
+
+// Register adds the exif command to r.
+func Register(r *registry.Registry) {
+	t := New()
+	r.Add(registry.Entry{
+		Name:        t.Name(),
+		Binary:      "exiftool",
+		Category:    "General Tools",
+		Description: t.Description(),
+		Tool:        t,
 	})
 }
@@ -2,6 +2,7 @@
 package hashing
 
 import (
+	"coldcase/pkg/registry"
 	"coldcase/pkg/runner"
 )
 
@@ -26,3 +27,15 @@ func Tools() []*HashTool {
 		{"tlsh", "Trend Micro Locality Sensitive Hash — similarity scoring for malware"},
 	}
 }
+
+// Register adds the hashing tools to r.
+func Register(r *registry.Registry) {
+	for _, t := range Tools() {
+		r.Add(registry.Entry{
+			Name:        t.name,
+			Category:    "Hashing & Verification",
+			Description: t.desc,
+			Tool:        t,
+		})
+	}
+}
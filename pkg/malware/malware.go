@@ -2,6 +2,7 @@
 package malware
 
 import (
+	"coldcase/pkg/registry"
 	"coldcase/pkg/runner"
 )
 
@@ -19,7 +20,19 @@ func (m *MalwareTool) Run(args []string) error {
 	if bin == "" {
 		bin = m.name
 	}
-	return runner.Run(runner.RunOpts{Binary: bin, Args: args})
+	return runner.Run(runner.RunOpts{Binary: bin, Args: args, Embedded: embeddedPaths[m.name], Network: networks[m.name]})
+}
+
+// networks opts tools that query online services in to outbound access;
+// the rest run without a network so samples cannot phone home.
+var networks = map[string]runner.Network{
+	"vt": runner.NetworkBridge,
+}
+
+// embeddedPaths lists option values that carry a path after a key, such as
+// yara's "-x cuckoo=/case/report.json".
+var embeddedPaths = map[string][]runner.EmbeddedPath{
+	"yara": {{Flag: "-x", Sep: "="}, {Flag: "--module-data", Sep: "="}},
 }
 
 // Tools returns all malware analysis tools.
@@ -32,3 +45,16 @@ func Tools() []*MalwareTool {
 		{"vt", "vt", "VirusTotal CLI — hash and file lookups via API"},
 	}
 }
+
+// Register adds the malware analysis tools to r.
+func Register(r *registry.Registry) {
+	for _, t := range Tools() {
+		r.Add(registry.Entry{
+			Name:        t.name,
+			Binary:      t.bin,
+			Category:    "Malware & Pattern Matching",
+			Description: t.desc,
+			Tool:        t,
+		})
+	}
+}
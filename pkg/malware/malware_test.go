@@ -0,0 +1,39 @@
+package malware
+
+import (
+	"testing"
+
+	"coldcase/pkg/runner/runnertest"
+)
+
+func TestYaraArgumentPaths(t *testing.T) {
+	var yara *MalwareTool
+	for _, m := range Tools() {
+		if m.name == "yara" {
+			yara = m
+		}
+	}
+	runnertest.Check(t, []runnertest.Case{
+		{
+			Name:   "module data after key=",
+			Files:  []string{"rules/cuckoo.yar", "reports/cuckoo.json", "samples/x.exe"},
+			Args:   []string{"-x", "cuckoo=reports/cuckoo.json", "rules/cuckoo.yar", "samples/x.exe"},
+			Want:   []string{"-x", "cuckoo=/data/vol0/cuckoo.json", "/data/vol1/cuckoo.yar", "/data/vol2/x.exe"},
+			Mounts: []string{"$D/reports:/data/vol0:ro", "$D/rules:/data/vol1:ro", "$D/samples:/data/vol2:ro"},
+		},
+		{
+			Name:   "--module-data=key=value",
+			Files:  []string{"reports/cuckoo.json", "rule.yar", "x.exe"},
+			Args:   []string{"--module-data=cuckoo=reports/cuckoo.json", "rule.yar", "x.exe"},
+			Want:   []string{"--module-data=cuckoo=/data/vol0/cuckoo.json", "/data/vol1/rule.yar", "/data/vol1/x.exe"},
+			Mounts: []string{"$D/reports:/data/vol0:ro", "$D:/data/vol1:ro"},
+		},
+		{
+			Name:   "comma-separated rule list",
+			Files:  []string{"rules/a.yar", "rules/b.yar", "samples/x.exe"},
+			Args:   []string{"-r", "rules/a.yar,rules/b.yar", "samples/x.exe"},
+			Want:   []string{"-r", "/data/vol0/a.yar,/data/vol0/b.yar", "/data/vol1/x.exe"},
+			Mounts: []string{"$D/rules:/data/vol0:ro", "$D/samples:/data/vol1:ro"},
+		},
+	}, yara.Run)
+}
@@ -0,0 +1,286 @@
+// Package manifest loads declarative tool definitions so new tools can be
+// added to ColdCase without recompiling. Manifests are YAML or JSON files in
+// ~/.coldcase/tools.d/ and in the project's .coldcase/tools.d/ directory;
+// each one becomes a registry entry that runs through runner.Run like any
+// built-in tool.
+//
+// Example (tools.d/evtxecmd.yaml):
+//
+//	name: evtxecmd
+//	binary: EvtxECmd
+//	category: Windows Artifacts
+//	description: Parse Windows event logs (Eric Zimmerman)
+//	image: ghcr.io/example/ez-tools:1.4
+//	args:
+//	  inputs: [-f, -d]
+//	  outputs:
+//	    - {flag: --csv, dir: true}
+//	version: [--version]
+//	run_mode: container-only
+//	parser:
+//	  type: csv
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"coldcase/pkg/registry"
+	"coldcase/pkg/runner"
+	"coldcase/pkg/session"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultCategory is used when a manifest does not name one.
+const DefaultCategory = "Custom Tools"
+
+// ProjectDir is the manifest directory relative to the working directory.
+const ProjectDir = ".coldcase/tools.d"
+
+// Manifest is one declarative tool definition.
+type Manifest struct {
+	// Name is the CLI command name.
+	Name string `yaml:"name" json:"name"`
+	// Binary is the executable to run (default: Name).
+	Binary      string `yaml:"binary" json:"binary"`
+	Category    string `yaml:"category" json:"category"`
+	Description string `yaml:"description" json:"description"`
+	// Image is the container image to fall back to (default: the ColdCase image).
+	Image     string `yaml:"image" json:"image"`
+	NeedsRoot bool   `yaml:"needs_root" json:"needs_root"`
+	// Network opts the tool in to a container network: "bridge" or "host".
+	Network string `yaml:"network" json:"network"`
+	// RunMode is the tool's run-mode policy, e.g. "container-only".
+	RunMode string   `yaml:"run_mode" json:"run_mode"`
+	Args    ArgRoles `yaml:"args" json:"args"`
+	// Version are the arguments that print the tool version (default: --version).
+	Version []string `yaml:"version" json:"version"`
+	Parser  *Parser  `yaml:"parser" json:"parser"`
+
+	// Path is the file the manifest was loaded from.
+	Path string `yaml:"-" json:"-"`
+}
+
+// ArgRoles declares which options carry input and output paths.
+type ArgRoles struct {
+	// Inputs are flags whose values must be existing files or directories.
+	Inputs   []string       `yaml:"inputs" json:"inputs"`
+	Outputs  []OutputRole   `yaml:"outputs" json:"outputs"`
+	Embedded []EmbeddedRole `yaml:"embedded" json:"embedded"`
+}
+
+// OutputRole mirrors runner.OutputArg.
+type OutputRole struct {
+	Flag string `yaml:"flag" json:"flag"`
+	Dir  bool   `yaml:"dir" json:"dir"`
+}
+
+// EmbeddedRole mirrors runner.EmbeddedPath.
+type EmbeddedRole struct {
+	Flag   string `yaml:"flag" json:"flag"`
+	Prefix string `yaml:"prefix" json:"prefix"`
+	Sep    string `yaml:"sep" json:"sep"`
+}
+
+// Dirs returns the manifest directories in load order: the user directory
+// first, then the project directory.
+func Dirs() []string {
+	return []string{filepath.Join(session.BaseDir(), "tools.d"), ProjectDir}
+}
+
+// Load reads every *.yaml, *.yml and *.json manifest in dirs. Missing
+// directories are skipped; invalid files are reported in errs and skipped.
+func Load(dirs ...string) (ms []*Manifest, errs []error) {
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		for _, e := range entries {
+			switch strings.ToLower(filepath.Ext(e.Name())) {
+			case ".yaml", ".yml", ".json":
+			default:
+				continue
+			}
+			if e.IsDir() {
+				continue
+			}
+			m, err := LoadFile(filepath.Join(dir, e.Name()))
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			ms = append(ms, m)
+		}
+	}
+	return ms, errs
+}
+
+// LoadFile parses and validates a single manifest. Unknown fields are errors
+// so that typos do not silently drop an argument role.
+func LoadFile(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&m)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	m.Path = path
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &m, nil
+}
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+func (m *Manifest) validate() error {
+	if !validName.MatchString(m.Name) {
+		return fmt.Errorf("invalid tool name %q", m.Name)
+	}
+	if m.Binary == "" {
+		m.Binary = m.Name
+	}
+	if m.Category == "" {
+		m.Category = DefaultCategory
+	}
+	if m.Description == "" {
+		m.Description = fmt.Sprintf("Run %s (from %s)", m.Binary, filepath.Base(m.Path))
+	}
+	for _, f := range m.Args.Inputs {
+		if !strings.HasPrefix(f, "-") {
+			return fmt.Errorf("input role %q is not a flag", f)
+		}
+	}
+	for _, o := range m.Args.Outputs {
+		if !strings.HasPrefix(o.Flag, "-") {
+			return fmt.Errorf("output role %q is not a flag", o.Flag)
+		}
+	}
+	if _, err := runner.ParseMode(m.RunMode); err != nil {
+		return err
+	}
+	if _, err := runner.ParseNetwork(m.Network); err != nil {
+		return err
+	}
+	if m.Parser != nil {
+		if _, err := m.Parser.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Register adds the manifests to r. A manifest whose name is already taken
+// by a built-in tool, a tool group, one of the reserved names (the CLI's own
+// commands, such as "session") or an earlier manifest is skipped and
+// reported in errs.
+func Register(r *registry.Registry, ms []*Manifest, reserved []string) (errs []error) {
+	for _, m := range ms {
+		if slices.Contains(reserved, m.Name) {
+			errs = append(errs, fmt.Errorf("%s: tool name %q is reserved for the built-in 'coldcase %s' command", m.Path, m.Name, m.Name))
+			continue
+		}
+		if isGroup(r, m.Name) {
+			errs = append(errs, fmt.Errorf("%s: tool name %q is taken by the '%s' tool group", m.Path, m.Name, m.Name))
+			continue
+		}
+		if e, ok := r.Lookup(m.Name); ok {
+			errs = append(errs, fmt.Errorf("%s: tool %q already registered in %s", m.Path, m.Name, e.Category))
+			continue
+		}
+		t, err := NewTool(m)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		r.Add(registry.Entry{
+			Name:        m.Name,
+			Binary:      m.Binary,
+			Category:    m.Category,
+			Description: m.Description,
+			NeedsRoot:   m.NeedsRoot,
+			Mode:        m.RunMode,
+			Tool:        t,
+		})
+	}
+	return errs
+}
+
+// Tool runs a manifest-defined tool.
+type Tool struct {
+	m     *Manifest
+	parse func([]byte) ([]byte, error)
+}
+
+// NewTool builds the runnable tool for m.
+func NewTool(m *Manifest) (*Tool, error) {
+	t := &Tool{m: m}
+	if m.Parser != nil {
+		parse, err := m.Parser.compile()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Path, err)
+		}
+		t.parse = parse
+	}
+	return t, nil
+}
+
+func (t *Tool) Name() string        { return t.m.Name }
+func (t *Tool) Description() string { return t.m.Description }
+
+// Manifest returns the definition the tool was built from.
+func (t *Tool) Manifest() *Manifest { return t.m }
+
+func (t *Tool) Run(args []string) error {
+	opts := runner.RunOpts{
+		Binary:      t.m.Binary,
+		Args:        args,
+		NeedsRoot:   t.m.NeedsRoot,
+		Network:     runner.Network(t.m.Network),
+		VersionArgs: t.m.Version,
+		Inputs:      t.m.Args.Inputs,
+		Image:       t.m.Image,
+		Parse:       t.parse,
+		Mode:        runner.Mode(t.m.RunMode),
+	}
+	for _, o := range t.m.Args.Outputs {
+		opts.Outputs = append(opts.Outputs, runner.OutputArg{Flag: o.Flag, Dir: o.Dir})
+	}
+	for _, e := range t.m.Args.Embedded {
+		opts.Embedded = append(opts.Embedded, runner.EmbeddedPath{Flag: e.Flag, Prefix: e.Prefix, Sep: e.Sep})
+	}
+	return runner.Run(opts)
+}
+
+// isGroup reports whether name is the parent command of a tool group.
+func isGroup(r *registry.Registry, name string) bool {
+	for _, e := range r.Entries() {
+		if e.Group == name {
+			return true
+		}
+	}
+	return false
+}
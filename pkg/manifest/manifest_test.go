@@ -0,0 +1,39 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+
+	"coldcase/pkg/registry"
+)
+
+func TestRegisterRejectsReservedNames(t *testing.T) {
+	r := registry.New()
+	r.Add(registry.Entry{Name: "psort", Group: "plaso", Category: "Timeline"})
+	r.Add(registry.Entry{Name: "tshark", Category: "Network"})
+
+	var ms []*Manifest
+	for _, name := range []string{"session", "plaso", "tshark", "mytool"} {
+		m := &Manifest{Name: name, Path: name + ".yaml"}
+		if err := m.validate(); err != nil {
+			t.Fatal(err)
+		}
+		ms = append(ms, m)
+	}
+	errs := Register(r, ms, []string{"session", "evidence", "help"})
+
+	if len(errs) != 3 {
+		t.Fatalf("errs = %v, want 3 rejections", errs)
+	}
+	for i, want := range []string{"reserved for the built-in 'coldcase session'", "'plaso' tool group", "tshark"} {
+		if !strings.Contains(errs[i].Error(), want) {
+			t.Errorf("errs[%d] = %v, want mention of %q", i, errs[i], want)
+		}
+	}
+	if _, ok := r.Lookup("mytool"); !ok {
+		t.Error("mytool not registered")
+	}
+	if _, ok := r.Lookup("session"); ok {
+		t.Error("session registered as a tool")
+	}
+}
@@ -0,0 +1,152 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// Parser describes how a tool's stdout is turned into structured records.
+//
+// Types:
+//
+//	json   stdout is a single JSON document
+//	jsonl  one JSON value per line
+//	csv    comma-separated rows; the first row is the header
+//	lines  each non-empty line is a record
+//	regex  each line matching Pattern becomes a record of its named groups
+type Parser struct {
+	Type    string `yaml:"type" json:"type"`
+	Pattern string `yaml:"pattern" json:"pattern"`
+}
+
+// parsed is the document saved next to the raw output.
+type parsed struct {
+	Parser  string `json:"parser"`
+	Records any    `json:"records"`
+}
+
+func (p *Parser) compile() (func([]byte) ([]byte, error), error) {
+	var records func([]byte) (any, error)
+	switch p.Type {
+	case "json":
+		records = parseJSON
+	case "jsonl":
+		records = parseJSONLines
+	case "csv":
+		records = parseCSV
+	case "lines":
+		records = parseLines
+	case "regex":
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("parser pattern: %w", err)
+		}
+		if len(re.SubexpNames()) < 2 {
+			return nil, fmt.Errorf("parser pattern needs at least one capture group")
+		}
+		records = func(out []byte) (any, error) { return parseRegex(re, out) }
+	default:
+		return nil, fmt.Errorf("unknown parser type %q (want json, jsonl, csv, lines or regex)", p.Type)
+	}
+
+	typ := p.Type
+	return func(out []byte) ([]byte, error) {
+		recs, err := records(out)
+		if err != nil {
+			return nil, fmt.Errorf("%s parser: %w", typ, err)
+		}
+		return json.MarshalIndent(parsed{Parser: typ, Records: recs}, "", "  ")
+	}, nil
+}
+
+func parseJSON(out []byte) (any, error) {
+	var v any
+	if err := json.Unmarshal(out, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func parseJSONLines(out []byte) (any, error) {
+	recs := []any{}
+	for i, line := range splitLines(out) {
+		var v any
+		if err := json.Unmarshal(line, &v); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		recs = append(recs, v)
+	}
+	return recs, nil
+}
+
+func parseCSV(out []byte) (any, error) {
+	r := csv.NewReader(bytes.NewReader(out))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	recs := []map[string]string{}
+	if len(rows) == 0 {
+		return recs, nil
+	}
+	header := rows[0]
+	for _, row := range rows[1:] {
+		rec := make(map[string]string, len(header))
+		for i, v := range row {
+			key := fmt.Sprintf("column%d", i+1)
+			if i < len(header) && header[i] != "" {
+				key = header[i]
+			}
+			rec[key] = v
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+func parseLines(out []byte) (any, error) {
+	recs := []string{}
+	for _, line := range splitLines(out) {
+		recs = append(recs, string(line))
+	}
+	return recs, nil
+}
+
+func parseRegex(re *regexp.Regexp, out []byte) (any, error) {
+	names := re.SubexpNames()
+	recs := []map[string]string{}
+	for _, line := range splitLines(out) {
+		m := re.FindSubmatch(line)
+		if m == nil {
+			continue
+		}
+		rec := make(map[string]string)
+		for i := 1; i < len(m); i++ {
+			key := names[i]
+			if key == "" {
+				key = fmt.Sprintf("group%d", i)
+			}
+			rec[key] = string(m[i])
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+// splitLines returns the non-blank lines of out without line endings.
+func splitLines(out []byte) [][]byte {
+	var lines [][]byte
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		if line := bytes.TrimSpace(sc.Bytes()); len(line) > 0 {
+			lines = append(lines, append([]byte(nil), line...))
+		}
+	}
+	return lines
+}
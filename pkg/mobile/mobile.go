@@ -2,6 +2,7 @@
 package mobile
 
 import (
+	"coldcase/pkg/registry"
 	"coldcase/pkg/runner"
 )
 
@@ -19,7 +20,13 @@ func (m *MobileTool) Run(args []string) error {
 	if bin == "" {
 		bin = m.name
 	}
-	return runner.Run(runner.RunOpts{Binary: bin, Args: args})
+	return runner.Run(runner.RunOpts{Binary: bin, Args: args, Outputs: outputArgs[m.name]})
+}
+
+// outputArgs lists the options through which each tool writes its results.
+var outputArgs = map[string][]runner.OutputArg{
+	"aleapp": {{Flag: "-o", Dir: true}, {Flag: "--output_path", Dir: true}},
+	"ileapp": {{Flag: "-o", Dir: true}, {Flag: "--output_path", Dir: true}},
 }
 
 // Tools returns all mobile forensics tools.
@@ -31,3 +38,16 @@ func Tools() []*MobileTool {
 		{"ideviceinfo", "", "iOS device information and artifact extraction (libimobiledevice)"},
 	}
 }
+
+// Register adds the mobile forensics tools to r.
+func Register(r *registry.Registry) {
+	for _, t := range Tools() {
+		r.Add(registry.Entry{
+			Name:        t.name,
+			Binary:      t.bin,
+			Category:    "Mobile Forensics",
+			Description: t.desc,
+			Tool:        t,
+		})
+	}
+}
@@ -3,6 +3,7 @@
 package network
 
 import (
+	"coldcase/pkg/registry"
 	"coldcase/pkg/runner"
 )
 
@@ -20,9 +21,38 @@ func (n *NetworkTool) Run(args []string) error {
 		Binary:    n.name,
 		Args:      args,
 		NeedsRoot: n.needsRoot,
+		Network:   networks[n.name],
+		Outputs:   outputArgs[n.name],
+		Embedded:  embeddedPaths[n.name],
 	})
 }
 
+// networks opts live-capture and replay tools in to the host network, so they
+// see the real interfaces when run in a container. Everything else runs
+// without a network.
+var networks = map[string]runner.Network{
+	"tcpdump":   runner.NetworkHost,
+	"zeek":      runner.NetworkHost,
+	"ngrep":     runner.NetworkHost,
+	"tcpreplay": runner.NetworkHost,
+	"argus":     runner.NetworkHost,
+	"p0f":       runner.NetworkHost,
+}
+
+// outputArgs lists the options through which each tool writes its results.
+var outputArgs = map[string][]runner.OutputArg{
+	"tshark":  {{Flag: "-w"}},
+	"tcpdump": {{Flag: "-w"}},
+	"tcpflow": {{Flag: "-o", Dir: true}},
+	"pcapfix": {{Flag: "-o"}, {Flag: "--outfile"}},
+}
+
+// embeddedPaths lists option values that carry a path after a key, such as
+// tshark's "-o tls.keylog_file:/case/keys.log" or "-X lua_script:dissector.lua".
+var embeddedPaths = map[string][]runner.EmbeddedPath{
+	"tshark": {{Flag: "-o", Sep: ":"}, {Flag: "-X", Sep: ":"}},
+}
+
 // Tools returns all network forensics tools.
 func Tools() []*NetworkTool {
 	return []*NetworkTool{
@@ -39,3 +69,16 @@ func Tools() []*NetworkTool {
 		{"networkminer", "Network Forensic Analysis Tool (CLI)", false},
 	}
 }
+
+// Register adds the network forensics tools to r.
+func Register(r *registry.Registry) {
+	for _, t := range Tools() {
+		r.Add(registry.Entry{
+			Name:        t.name,
+			Category:    "Network Forensics",
+			Description: t.desc,
+			NeedsRoot:   t.needsRoot,
+			Tool:        t,
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package network
+
+import (
+	"testing"
+
+	"coldcase/pkg/runner/runnertest"
+)
+
+func tool(t *testing.T, name string) *NetworkTool {
+	t.Helper()
+	for _, n := range Tools() {
+		if n.name == name {
+			return n
+		}
+	}
+	t.Fatalf("no tool %s", name)
+	return nil
+}
+
+func TestTsharkArgumentPaths(t *testing.T) {
+	runnertest.Check(t, []runnertest.Case{
+		{
+			Name:   "attached short value, embedded keylog path and output file",
+			Files:  []string{"cap/dump.pcap", "keys/tls.log"},
+			Args:   []string{"-rcap/dump.pcap", "-o", "tls.keylog_file:keys/tls.log", "-w", "cap/http.pcap", "-Y", "http"},
+			Want:   []string{"-r/data/vol0/dump.pcap", "-o", "tls.keylog_file:/data/vol1/tls.log", "-w", "/data/out2/http.pcap", "-Y", "http"},
+			Mounts: []string{"$D/cap:/data/vol0:ro", "$D/keys:/data/vol1:ro", "$D/cap/http.pcap:/data/out2/http.pcap:rw"},
+		},
+		{
+			Name:   "lua script and display filter",
+			Files:  []string{"dump.pcap", "lua/dissector.lua"},
+			Args:   []string{"-r", "dump.pcap", "-X", "lua_script:lua/dissector.lua", "-Y", "ip.addr==10.0.0.1"},
+			Want:   []string{"-r", "/data/vol0/dump.pcap", "-X", "lua_script:/data/vol1/dissector.lua", "-Y", "ip.addr==10.0.0.1"},
+			Mounts: []string{"$D:/data/vol0:ro", "$D/lua:/data/vol1:ro"},
+		},
+	}, tool(t, "tshark").Run)
+}
+
+func TestTcpflowOutputDirectory(t *testing.T) {
+	runnertest.Check(t, []runnertest.Case{{
+		Name:   "output directory",
+		Files:  []string{"dump.pcap"},
+		Args:   []string{"-r", "dump.pcap", "-o", "flows"},
+		Want:   []string{"-r", "/data/vol0/dump.pcap", "-o", "/data/out1"},
+		Mounts: []string{"$D:/data/vol0:ro", "$D/flows:/data/out1:rw"},
+	}}, tool(t, "tcpflow").Run)
+}
@@ -0,0 +1,130 @@
+// Package registry is the central catalogue of every tool ColdCase exposes.
+// Tool packages add their entries through a Register function; the CLI then
+// generates the command tree, `list`, `check` and `deps check` from it, so
+// there is a single place that knows which tools exist.
+package registry
+
+import (
+	"strings"
+
+	"coldcase/pkg/tools"
+)
+
+// Dependency is an extra prerequisite of a tool beyond its binary,
+// such as a bundled script directory.
+type Dependency struct {
+	Name  string
+	Check func() bool
+}
+
+// Entry describes one registered tool.
+type Entry struct {
+	// Name is the CLI command name (e.g. "pdfid").
+	Name string
+	// Group optionally nests the command under a parent (e.g. "plaso").
+	Group string
+	// Binary is the host executable the tool needs (python3 for scripts).
+	Binary string
+	// Category is the heading the tool is listed under.
+	Category    string
+	Description string
+	// NeedsRoot marks tools that need elevated privileges or raw network access.
+	NeedsRoot bool
+	// Deps are checked by `check` and `deps check` alongside Binary.
+	Deps []Dependency
+	// Mode is the tool's own run-mode policy (e.g. "container-only"), if any.
+	Mode string
+	// Tool runs the command.
+	Tool tools.Tool
+}
+
+// Command returns the full CLI invocation, e.g. "plaso parse".
+func (e Entry) Command() string {
+	if e.Group == "" {
+		return e.Name
+	}
+	return e.Group + " " + e.Name
+}
+
+// Registry holds entries in registration order.
+type Registry struct {
+	entries    []Entry
+	categories []string
+}
+
+// New returns an empty registry.
+func New() *Registry { return &Registry{} }
+
+// Add registers an entry. Binary defaults to the entry name.
+func (r *Registry) Add(e Entry) {
+	if e.Binary == "" {
+		e.Binary = e.Name
+	}
+	if !contains(r.categories, e.Category) {
+		r.categories = append(r.categories, e.Category)
+	}
+	r.entries = append(r.entries, e)
+}
+
+// Entries returns every registered entry.
+func (r *Registry) Entries() []Entry { return r.entries }
+
+// Categories returns category names in the order they were first registered.
+func (r *Registry) Categories() []string { return r.categories }
+
+// ByCategory returns the entries registered under category.
+func (r *Registry) ByCategory(category string) []Entry {
+	var out []Entry
+	for _, e := range r.entries {
+		if e.Category == category {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Lookup finds an entry by its full command (e.g. "tshark" or "plaso parse").
+func (r *Registry) Lookup(command string) (Entry, bool) {
+	command = strings.TrimSpace(command)
+	for _, e := range r.entries {
+		if e.Command() == command {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Binaries returns every distinct binary required by registered tools.
+func (r *Registry) Binaries() []string {
+	var out []string
+	for _, e := range r.entries {
+		if !contains(out, e.Binary) {
+			out = append(out, e.Binary)
+		}
+	}
+	return out
+}
+
+// Dependencies returns every distinct non-binary dependency.
+func (r *Registry) Dependencies() []Dependency {
+	var out []Dependency
+	var seen []string
+	for _, e := range r.entries {
+		for _, d := range e.Deps {
+			if !contains(seen, d.Name) {
+				seen = append(seen, d.Name)
+				out = append(out, d)
+			}
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package runner
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// EmbeddedPath describes a tool-specific option value that carries a path
+// inside a larger string, such as tshark's "-o tls.keylog_file:/keys.log" or
+// vol.py's "--single-location file:///evidence/mem.raw".
+type EmbeddedPath struct {
+	// Flag is the option that takes the value; empty matches any argument.
+	Flag string
+	// Prefix is literal text that precedes the path, e.g. "file://".
+	Prefix string
+	// Sep splits "key<Sep>path" values; the path follows the first Sep.
+	Sep string
+}
+
+// pathRef is a host path found in a tool's arguments.
+type pathRef struct {
+	// Host is the path as it appeared in the argument.
+	Host string
+	// Output marks paths the tool writes to; Dir marks output directories.
+	Output bool
+	Dir    bool
+}
+
+// argSpec is the per-tool knowledge used to find paths in arguments.
+type argSpec struct {
+	outputs  []OutputArg
+	embedded []EmbeddedPath
+}
+
+func specFor(opts RunOpts) argSpec {
+	return argSpec{outputs: opts.Outputs, embedded: opts.Embedded}
+}
+
+// listSeps are the separators accepted for lists of paths in one argument.
+var listSeps = []string{",", ";"}
+
+// rewriteArgs finds every path in args and replaces it with mapFn's result.
+// It understands:
+//
+//   - separate values:       -f /evidence/mem.raw
+//   - "=" values:            --file=/evidence/mem.raw
+//   - attached short values: -r./dump.pcap
+//   - bare relative names:   mem.raw (when it exists in the working directory)
+//   - path lists:            /a/rules.yar,/b/rules.yar
+//   - tool-specific embedded paths declared through EmbeddedPath
+//
+// Input paths are only recognised when they exist; output paths (declared
+// through OutputArg or --output-dir) are recognised whether or not they exist.
+func rewriteArgs(args []string, spec argSpec, mapFn func(pathRef) string) []string {
+	out := make([]string, 0, len(args))
+	pending := "" // last flag seen, which the next value may belong to
+	for _, arg := range args {
+		if !isFlag(arg) {
+			out = append(out, rewriteValue(pending, arg, spec, mapFn))
+			pending = ""
+			continue
+		}
+		pending = ""
+
+		// --flag=value
+		if name, val, ok := strings.Cut(arg, "="); ok {
+			out = append(out, name+"="+rewriteValue(name, val, spec, mapFn))
+			continue
+		}
+
+		// -rVALUE: a short flag with its value attached.
+		if len(arg) > 2 && arg[1] != '-' {
+			name, val := arg[:2], arg[2:]
+			if _, isOut := outputFor(name, spec.outputs); isOut || pathExists(val) {
+				out = append(out, name+rewriteValue(name, val, spec, mapFn))
+				continue
+			}
+		}
+
+		out = append(out, arg)
+		pending = arg
+	}
+	return out
+}
+
+// rewriteValue maps the paths inside a single option value.
+func rewriteValue(flag, val string, spec argSpec, mapFn func(pathRef) string) string {
+	if val == "" {
+		return val
+	}
+	if o, ok := outputFor(flag, spec.outputs); ok {
+		return mapFn(pathRef{Host: val, Output: true, Dir: o.Dir})
+	}
+
+	for _, e := range spec.embedded {
+		if e.Flag != "" && e.Flag != flag {
+			continue
+		}
+		if e.Prefix != "" && strings.HasPrefix(val, e.Prefix) {
+			if rest := val[len(e.Prefix):]; pathExists(rest) {
+				return e.Prefix + mapFn(pathRef{Host: rest})
+			}
+		}
+		if e.Sep != "" {
+			if key, rest, ok := strings.Cut(val, e.Sep); ok && pathExists(rest) {
+				return key + e.Sep + mapFn(pathRef{Host: rest})
+			}
+		}
+	}
+
+	if pathExists(val) {
+		return mapFn(pathRef{Host: val})
+	}
+
+	for _, sep := range listSeps {
+		parts := strings.Split(val, sep)
+		if len(parts) < 2 || !allExist(parts) {
+			continue
+		}
+		for i, p := range parts {
+			parts[i] = mapFn(pathRef{Host: p})
+		}
+		return strings.Join(parts, sep)
+	}
+	return val
+}
+
+// inputPaths returns the absolute host paths of every existing input found
+// in args, for hashing into the session log.
+func inputPaths(args []string, spec argSpec) []string {
+	var paths []string
+	rewriteArgs(args, spec, func(r pathRef) string {
+		if !r.Output {
+			if abs, err := filepath.Abs(r.Host); err == nil {
+				paths = append(paths, abs)
+			}
+		}
+		return r.Host
+	})
+	return paths
+}
+
+// outputPaths returns the output references found in args.
+func outputPaths(args []string, spec argSpec) []pathRef {
+	var refs []pathRef
+	rewriteArgs(args, spec, func(r pathRef) string {
+		if r.Output {
+			refs = append(refs, r)
+		}
+		return r.Host
+	})
+	return refs
+}
+
+// missingInputs returns the values of the given input flags that do not
+// exist on the host.
+func missingInputs(args []string, flags []string) []string {
+	if len(flags) == 0 {
+		return nil
+	}
+	isInput := func(flag string) bool {
+		for _, f := range flags {
+			if f == flag {
+				return true
+			}
+		}
+		return false
+	}
+
+	var missing []string
+	check := func(val string) {
+		if val != "" && !pathExists(val) {
+			missing = append(missing, val)
+		}
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch name, val, ok := strings.Cut(arg, "="); {
+		case ok && isInput(name):
+			check(val)
+		case isInput(arg) && i+1 < len(args):
+			i++
+			check(args[i])
+		case len(arg) > 2 && arg[1] != '-' && isFlag(arg) && isInput(arg[:2]):
+			check(arg[2:])
+		}
+	}
+	return missing
+}
+
+func isFlag(arg string) bool {
+	return len(arg) > 1 && arg[0] == '-'
+}
+
+func allExist(paths []string) bool {
+	for _, p := range paths {
+		if p == "" || !pathExists(p) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package runner
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestDetectMounts runs invocations that need no tool-specific spec through
+// detectMounts; the tool packages test their own Outputs and Embedded specs
+// through ContainerMounts. Paths are relative to a scratch directory holding
+// files; "$D" in the wanted mounts stands for that directory.
+func TestDetectMounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  []string
+		spec   argSpec
+		args   []string
+		want   []string // rewritten argv
+		mounts []string
+	}{
+		{
+			name:   "comma-separated list",
+			files:  []string{"rules/a.yar", "rules/b.yar", "samples/x.exe"},
+			spec:   argSpec{},
+			args:   []string{"-r", "rules/a.yar,rules/b.yar", "samples/x.exe"},
+			want:   []string{"-r", "/data/vol0/a.yar,/data/vol0/b.yar", "/data/vol1/x.exe"},
+			mounts: []string{"$D/rules:/data/vol0:ro", "$D/samples:/data/vol1:ro"},
+		},
+		{
+			name:   "semicolon list with a missing element is left alone",
+			files:  []string{"rules/a.yar"},
+			spec:   argSpec{},
+			args:   []string{"rules/a.yar;rules/missing.yar"},
+			want:   []string{"rules/a.yar;rules/missing.yar"},
+			mounts: nil,
+		},
+		{
+			name:   "same basename in different directories",
+			files:  []string{"a/evil.bin", "b/evil.bin"},
+			spec:   argSpec{},
+			args:   []string{"a/evil.bin", "b/evil.bin"},
+			want:   []string{"/data/vol0/evil.bin", "/data/vol1/evil.bin"},
+			mounts: []string{"$D/a:/data/vol0:ro", "$D/b:/data/vol1:ro"},
+		},
+		{
+			name:   "same directory mounted once",
+			files:  []string{"ev/one", "ev/two"},
+			spec:   argSpec{},
+			args:   []string{"ev/one", "--other=ev/two"},
+			want:   []string{"/data/vol0/one", "--other=/data/vol0/two"},
+			mounts: []string{"$D/ev:/data/vol0:ro"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := inTempDir(t, tc.files...)
+			mounts, got := detectMounts(tc.args, tc.spec)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("argv:\n got  %q\n want %q", got, tc.want)
+			}
+			var want []string
+			for _, m := range tc.mounts {
+				want = append(want, strings.ReplaceAll(m, "$D", dir))
+			}
+			if !slices.Equal(mounts, want) {
+				t.Errorf("mounts:\n got  %q\n want %q", mounts, want)
+			}
+		})
+	}
+}
+
+func TestInputAndOutputPaths(t *testing.T) {
+	dir := inTempDir(t, "cap/dump.pcap", "keys/tls.log")
+	spec := argSpec{
+		outputs:  []OutputArg{{Flag: "-w"}},
+		embedded: []EmbeddedPath{{Flag: "-o", Sep: ":"}},
+	}
+	args := []string{"-r", "cap/dump.pcap", "-o", "tls.keylog_file:keys/tls.log", "-w", "out.pcap"}
+
+	inputs := inputPaths(args, spec)
+	if want := []string{dir + "/cap/dump.pcap", dir + "/keys/tls.log"}; !slices.Equal(inputs, want) {
+		t.Errorf("inputs = %q, want %q", inputs, want)
+	}
+	outputs := outputPaths(args, spec)
+	if len(outputs) != 1 || outputs[0].Host != "out.pcap" || outputs[0].Dir {
+		t.Errorf("outputs = %+v", outputs)
+	}
+}
@@ -0,0 +1,129 @@
+package runner
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"coldcase/pkg/session"
+)
+
+// DefaultTimeout applies to every run whose RunOpts.Timeout is zero.
+// It is set from the global --timeout flag; zero means no limit.
+var DefaultTimeout time.Duration
+
+// killGrace is how long a cancelled tool gets to exit after the forwarded
+// signal before it is killed outright.
+const killGrace = 10 * time.Second
+
+// interruptError is the cancellation cause when the operator sends a signal.
+type interruptError struct{ sig os.Signal }
+
+func (e *interruptError) Error() string { return "interrupted by " + signalName(e.sig) }
+
+// runContext derives the context for one run: it applies the timeout and
+// cancels on SIGINT/SIGTERM instead of letting them kill ColdCase, so the
+// command can still be logged. Call stop once the tool has exited.
+func runContext(opts RunOpts) (ctx context.Context, stop func()) {
+	ctx = opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	cancelTimeout := func() {}
+	if timeout > 0 {
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-sigs:
+			cancel(&interruptError{sig})
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigs)
+		close(done)
+		cancel(nil)
+		cancelTimeout()
+	}
+}
+
+// forwardSignal picks the signal to deliver to a cancelled tool: the one the
+// operator sent, or SIGTERM for timeouts.
+func forwardSignal(ctx context.Context) os.Signal {
+	var ie *interruptError
+	if errors.As(context.Cause(ctx), &ie) {
+		return ie.sig
+	}
+	return syscall.SIGTERM
+}
+
+// signalName returns the name docker/podman expect for --signal.
+func signalName(sig os.Signal) string {
+	if sig == os.Interrupt {
+		return "SIGINT"
+	}
+	return "SIGTERM"
+}
+
+// bindNative makes cmd forward the cancellation signal to the process.
+func bindNative(ctx context.Context, cmd *exec.Cmd) {
+	cmd.Cancel = func() error { return cmd.Process.Signal(forwardSignal(ctx)) }
+	cmd.WaitDelay = killGrace
+}
+
+// bindContainer makes cmd stop the named container on cancellation. Killing
+// only the docker client would leave the container running.
+func bindContainer(ctx context.Context, cmd *exec.Cmd, runtime, name string) {
+	cmd.Cancel = func() error {
+		_ = exec.Command(runtime, "kill", "--signal", signalName(forwardSignal(ctx)), name).Run()
+		return nil
+	}
+	cmd.WaitDelay = killGrace
+}
+
+// cleanupContainer force-removes a container left behind by a cancelled run.
+func cleanupContainer(ctx context.Context, runtime, name string) {
+	if ctx.Err() != nil {
+		_ = exec.Command(runtime, "rm", "-f", name).Run()
+	}
+}
+
+// containerName returns a unique name so a run can be addressed by docker kill.
+func containerName() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return "coldcase-" + hex.EncodeToString(b)
+}
+
+// runStatus classifies how a run ended and returns the error to report.
+func runStatus(ctx context.Context, runErr error) (session.CommandStatus, error) {
+	if ctx.Err() != nil {
+		cause := context.Cause(ctx)
+		if errors.Is(cause, context.DeadlineExceeded) {
+			return session.StatusTimedOut, fmt.Errorf("timed out: %w", cause)
+		}
+		return session.StatusInterrupted, cause
+	}
+	if runErr != nil {
+		return session.StatusFailed, runErr
+	}
+	return session.StatusCompleted, nil
+}
@@ -0,0 +1,102 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"coldcase/pkg/session"
+)
+
+// resolveEvidence replaces every evidence reference in args ("@EV-001", alone
+// or as the value of "--flag=@EV-001") with the path of that registered
+// evidence in s, and returns the IDs it resolved.
+func resolveEvidence(m *session.Manager, s *session.Session, args []string) ([]string, []string, error) {
+	out := make([]string, len(args))
+	var ids []string
+	for i, arg := range args {
+		prefix, val := "", arg
+		if name, v, ok := strings.Cut(arg, "="); ok && isFlag(name) {
+			prefix, val = name+"=", v
+		}
+		id, ok := strings.CutPrefix(val, session.EvidenceRef)
+		if !ok || !strings.HasPrefix(id, session.EvidenceIDPrefix) {
+			out[i] = arg
+			continue
+		}
+		if s == nil {
+			return nil, nil, fmt.Errorf("evidence reference %s needs an active session", val)
+		}
+		ev, ok := s.EvidenceByID(id)
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown evidence ID %s in session '%s'", id, s.ID)
+		}
+		out[i] = prefix + m.EvidencePath(s, ev)
+		if !contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return out, ids, nil
+}
+
+// Evidence drift policies, selected through the "evidence_drift" setting.
+const (
+	// DriftRefuse refuses to run a command whose evidence inputs changed
+	// since acquisition (default).
+	DriftRefuse = "refuse"
+	// DriftWarn runs it anyway, with a warning recorded in the entry.
+	DriftWarn = "warn"
+)
+
+// DriftPolicies lists the valid evidence_drift settings.
+var DriftPolicies = []string{DriftRefuse, DriftWarn}
+
+// EvidenceDrift decides what happens when registered evidence changed. The
+// CLI sets it from the resolved "evidence_drift" setting.
+var EvidenceDrift = DriftRefuse
+
+// ParseDriftPolicy validates an evidence_drift setting; the empty string
+// means DriftRefuse.
+func ParseDriftPolicy(s string) (string, error) {
+	if s == "" {
+		return DriftRefuse, nil
+	}
+	for _, p := range DriftPolicies {
+		if s == p {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown evidence drift policy %q (valid: %s)", s, strings.Join(DriftPolicies, ", "))
+}
+
+// checkDrift compares every input in opts that is registered evidence with
+// its acquisition record before the command runs. Changed content refuses
+// the run under DriftRefuse; otherwise each drift is reported loudly and
+// returned for the entry's drift_warnings.
+func checkDrift(m *session.Manager, s *session.Session, opts RunOpts) ([]string, error) {
+	var warnings []string
+	for _, p := range inputPaths(opts.Args, specFor(opts)) {
+		d, err := m.CheckEvidence(s, p)
+		if err != nil {
+			return nil, fmt.Errorf("cannot check evidence %s: %w", p, err)
+		}
+		if d == nil {
+			continue
+		}
+		if d.ContentChanged && EvidenceDrift == DriftRefuse {
+			return nil, fmt.Errorf("evidence integrity check failed: %s (run 'coldcase evidence verify'; set evidence_drift=warn to run anyway)", d)
+		}
+		fmt.Fprintf(os.Stderr, "[!] EVIDENCE INTEGRITY WARNING: %s\n", d)
+		warnings = append(warnings, d.String())
+	}
+	return warnings, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"coldcase/pkg/session"
+)
+
+// Executor is an execution backend. Run wraps every executor in the same
+// session handling (input checks, output capture, input hashing, parsing
+// and logging), so a backend only has to start the tool and describe it.
+// Executors holding connections may also implement io.Closer; Run closes
+// them once the command is logged.
+type Executor interface {
+	// Name is recorded as the entry's run_mode, e.g. "native" or "container".
+	Name() string
+	// Prepare runs before any session output file is created and fills in
+	// what the log records about the job; an error refuses the run.
+	Prepare(job *Job) error
+	// Exec runs the tool, streaming its output to stdout and stderr.
+	Exec(ctx context.Context, job *Job, stdout, stderr io.Writer) error
+	// Version returns the tool's version line; ok is false when the probe
+	// failed and the result should not be cached.
+	Version(job *Job) (version string, ok bool)
+}
+
+// Job is one tool invocation as seen by an executor.
+type Job struct {
+	Opts RunOpts
+	// Logger and SessionID are set when a session is active.
+	Logger    *session.Logger
+	SessionID string
+
+	// Filled in by the executor for the session log.
+	ToolPath    string
+	Image       string
+	ImageDigest string
+	Isolation   *session.Isolation
+	Remote      *session.RemoteExec
+}
+
+// BackendLocal runs natively or in a container per the run-mode policy. It
+// and BackendSSH, defined with its executor in ssh.go, are the backends
+// selectable through the "executor" setting.
+const BackendLocal = "local"
+
+// BackendFake is the name Fake records as the entry's run_mode. It is not a
+// setting: Fake is only reachable through SetExecutor, so configuration
+// cannot make a session log runs that never happened.
+const BackendFake = "fake"
+
+// Backends lists the valid executor settings.
+var Backends = []string{BackendLocal, BackendSSH}
+
+// Backend selects the executor for every run. The CLI sets it from the
+// resolved "executor" setting.
+var Backend = BackendLocal
+
+// ParseBackend validates an executor setting; the empty string means BackendLocal.
+func ParseBackend(s string) (string, error) {
+	if s == "" {
+		return BackendLocal, nil
+	}
+	for _, b := range Backends {
+		if s == b {
+			return b, nil
+		}
+	}
+	return "", fmt.Errorf("unknown executor %q (valid: %s)", s, strings.Join(Backends, ", "))
+}
+
+// override replaces executor selection; see SetExecutor.
+var override Executor
+
+// SetExecutor makes every run use e regardless of configuration, e.g. a
+// *Fake in tests. It returns a function that restores normal selection.
+func SetExecutor(e Executor) (restore func()) {
+	prev := override
+	override = e
+	return func() { override = prev }
+}
+
+// selectExecutor picks the executor for opts. policy is the effective
+// run-mode policy, which every backend must satisfy; it is empty only under
+// SetExecutor.
+func selectExecutor(sess *session.Session, opts RunOpts) (e Executor, policy Mode, err error) {
+	if override != nil {
+		return override, "", nil
+	}
+	mode, source := effectiveMode(sess, toolModeFor(opts))
+	if Backend == BackendSSH {
+		if err := remoteAllows(mode, source, opts.Binary); err != nil {
+			return nil, "", err
+		}
+		return &SSH{}, mode, nil
+	}
+	path, rt, err := route(mode, opts.Binary)
+	if err != nil {
+		return nil, "", err
+	}
+	if path == PathContainer {
+		return &Container{Runtime: rt}, mode, nil
+	}
+	return &Native{}, mode, nil
+}
+
+// remoteAllows reports whether the ssh executor can honour mode, which came
+// from the policy level source. The workstation runs the tool's native
+// binary; there is no image to pin, so a container-only policy cannot be
+// met remotely.
+func remoteAllows(mode Mode, source, binary string) error {
+	if mode == ModeContainerOnly {
+		return fmt.Errorf("executor %s runs '%s' natively on the remote host, but the %s run mode is %s", BackendSSH, binary, source, mode)
+	}
+	return nil
+}
+
+// ─── native ───────────────────────────────────────────────────────────────────
+
+// Native runs tools on the host, inside the configured sandbox if any.
+type Native struct {
+	sandbox *sandboxSpec
+}
+
+func (n *Native) Name() string { return PathNative }
+
+func (n *Native) Prepare(job *Job) error {
+	job.ToolPath = nativeToolPath(job.Opts.Binary)
+	kind, err := resolveSandbox()
+	if err != nil || kind == SandboxOff {
+		return err
+	}
+	spec, err := buildSandboxSpec(kind, job.Opts)
+	if err != nil {
+		return fmt.Errorf("cannot sandbox %s: %w", job.Opts.Binary, err)
+	}
+	iso := spec.isolation()
+	n.sandbox, job.Isolation = &spec, &iso
+	return nil
+}
+
+func (n *Native) Exec(ctx context.Context, job *Job, stdout, stderr io.Writer) error {
+	return runNative(ctx, job.Opts, n.sandbox, stdout, stderr)
+}
+
+func (n *Native) Version(job *Job) (string, bool) {
+	return probeVersion(job.Opts, "")
+}
+
+// ─── container ────────────────────────────────────────────────────────────────
+
+// Container runs tools in the ColdCase image through the docker or podman CLI.
+type Container struct {
+	Runtime string
+	iso     session.Isolation
+}
+
+func (c *Container) Name() string { return PathContainer }
+
+// Prepare pins the image so every command in a session runs the same image
+// even if the tag is moved meanwhile.
+func (c *Container) Prepare(job *Job) error {
+	c.iso = containerIsolation(job.Opts)
+	job.Isolation = &c.iso
+	job.Image = imageFor(job.Opts)
+	ref, digest, err := pinImage(job.Logger, job.SessionID, c.Runtime, job.Image)
+	if err != nil {
+		return err
+	}
+	job.Opts.Image, job.ImageDigest = ref, digest
+	job.ToolPath = containerToolPath(job.Image, digest, job.Opts.Binary)
+	return nil
+}
+
+func (c *Container) Exec(ctx context.Context, job *Job, stdout, stderr io.Writer) error {
+	return runInContainer(ctx, c.Runtime, job.Opts, c.iso, stdout, stderr)
+}
+
+func (c *Container) Version(job *Job) (string, bool) {
+	return probeVersion(job.Opts, c.Runtime)
+}
+
+// ─── fake ─────────────────────────────────────────────────────────────────────
+
+// Fake runs nothing. It writes Stdout and Stderr, returns Err and records
+// every job, for tests installed through SetExecutor. The zero value
+// reports each invocation on stderr and succeeds.
+type Fake struct {
+	Stdout string
+	Stderr string
+	Err    error
+	Jobs   []*Job
+}
+
+func (f *Fake) Name() string { return BackendFake }
+
+func (f *Fake) Prepare(job *Job) error {
+	job.ToolPath = "fake:" + job.Opts.Binary
+	return nil
+}
+
+func (f *Fake) Exec(ctx context.Context, job *Job, stdout, stderr io.Writer) error {
+	f.Jobs = append(f.Jobs, job)
+	if f.Stdout == "" && f.Stderr == "" && f.Err == nil {
+		fmt.Fprintf(stderr, "[fake] would run: %s %s\n", job.Opts.Binary, strings.Join(job.Opts.Args, " "))
+		return nil
+	}
+	io.WriteString(stdout, f.Stdout)
+	io.WriteString(stderr, f.Stderr)
+	return f.Err
+}
+
+func (f *Fake) Version(job *Job) (string, bool) { return "fake", true }
@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"fmt"
+
+	"coldcase/pkg/session"
+)
+
+// Network is the container network a tool is attached to. Container runs
+// get no network unless the tool opts in through its metadata.
+type Network string
+
+const (
+	NetworkNone   Network = "none"
+	NetworkBridge Network = "bridge" // outbound access, e.g. API lookups
+	NetworkHost   Network = "host"   // host interfaces, for live capture
+)
+
+// Networks lists the valid container networks.
+var Networks = []Network{NetworkNone, NetworkBridge, NetworkHost}
+
+// ParseNetwork validates a network name; the empty string means NetworkNone.
+func ParseNetwork(s string) (Network, error) {
+	if s == "" {
+		return NetworkNone, nil
+	}
+	for _, n := range Networks {
+		if Network(s) == n {
+			return n, nil
+		}
+	}
+	return "", fmt.Errorf("unknown network %q (valid: none, bridge, host)", s)
+}
+
+// containerIsolation returns the confinement for a container run of opts:
+// no network unless opted in, a read-only root filesystem with a tmpfs /tmp,
+// every capability dropped and no privilege escalation. NeedsRoot tools get
+// back only the raw-socket capabilities they need.
+func containerIsolation(opts RunOpts) session.Isolation {
+	network := opts.Network
+	if network == "" {
+		network = NetworkNone
+	}
+	iso := session.Isolation{
+		Network:         string(network),
+		ReadOnlyRoot:    true,
+		CapDrop:         []string{"ALL"},
+		NoNewPrivileges: true,
+		Tmpfs:           []string{"/tmp"},
+	}
+	if opts.NeedsRoot {
+		iso.CapAdd = []string{"NET_ADMIN", "NET_RAW"}
+	}
+	return iso
+}
+
+// isolationArgs renders iso as docker/podman run flags. HOME points at the
+// tmpfs so tools that write caches or settings still work on a read-only root.
+func isolationArgs(iso session.Isolation) []string {
+	args := []string{"--network", iso.Network}
+	if iso.ReadOnlyRoot {
+		args = append(args, "--read-only", "--env", "HOME=/tmp")
+	}
+	for _, c := range iso.CapDrop {
+		args = append(args, "--cap-drop", c)
+	}
+	for _, c := range iso.CapAdd {
+		args = append(args, "--cap-add", c)
+	}
+	if iso.NoNewPrivileges {
+		args = append(args, "--security-opt", "no-new-privileges")
+	}
+	for _, t := range iso.Tmpfs {
+		args = append(args, "--tmpfs", t)
+	}
+	return args
+}
@@ -0,0 +1,131 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"coldcase/pkg/session"
+	"coldcase/pkg/tools"
+)
+
+// Mode is the run-mode policy deciding between the native binary and the
+// container image.
+type Mode string
+
+const (
+	ModeNativeOnly      Mode = "native-only"
+	ModeContainerOnly   Mode = "container-only"
+	ModePreferNative    Mode = "prefer-native"
+	ModePreferContainer Mode = "prefer-container"
+)
+
+// Modes lists the valid policies.
+var Modes = []Mode{ModeNativeOnly, ModeContainerOnly, ModePreferNative, ModePreferContainer}
+
+// Execution paths recorded in CommandEntry.RunMode.
+const (
+	PathNative    = "native"
+	PathContainer = "container"
+)
+
+// DefaultMode is the global policy (config run_mode). ToolMode is the
+// per-tool policy for the current invocation (config run_mode.<tool>).
+// The CLI sets both from the resolved configuration.
+var (
+	DefaultMode = ModePreferNative
+	ToolMode    Mode
+)
+
+// ParseMode validates a policy name. An empty name is allowed and means
+// "not set at this level".
+func ParseMode(s string) (Mode, error) {
+	if s == "" {
+		return "", nil
+	}
+	for _, m := range Modes {
+		if string(m) == s {
+			return m, nil
+		}
+	}
+	names := make([]string, len(Modes))
+	for i, m := range Modes {
+		names[i] = string(m)
+	}
+	return "", fmt.Errorf("invalid run mode %q (want %s)", s, strings.Join(names, ", "))
+}
+
+// effectiveMode picks the policy for a run and names the level it came from.
+// A session policy wins over everything so that casework bound to the
+// container cannot be redirected from the command line; after that the most
+// specific level wins.
+func effectiveMode(sess *session.Session, toolMode Mode) (Mode, string) {
+	if sess != nil && sess.RunMode != "" {
+		return Mode(sess.RunMode), "session"
+	}
+	if toolMode != "" {
+		return toolMode, "tool"
+	}
+	if DefaultMode != "" {
+		return DefaultMode, "global"
+	}
+	return ModePreferNative, "default"
+}
+
+// route decides where binary runs under mode. It returns PathNative or
+// PathContainer, the container runtime for the latter, or an error when the
+// policy cannot be satisfied.
+func route(mode Mode, binary string) (path, runtime string, err error) {
+	native := tools.CheckToolInstalled(binary)
+	rt, rtErr := detectRuntime()
+
+	switch mode {
+	case ModeNativeOnly:
+		if native {
+			return PathNative, "", nil
+		}
+		return "", "", fmt.Errorf("'%s' not found on PATH and run mode is %s", binary, mode)
+	case ModeContainerOnly:
+		if rtErr == nil {
+			return PathContainer, rt, nil
+		}
+		return "", "", fmt.Errorf("run mode is %s but no container runtime is available: %w", mode, rtErr)
+	case ModePreferContainer:
+		if rtErr == nil {
+			return PathContainer, rt, nil
+		}
+		if native {
+			return PathNative, "", nil
+		}
+		return "", "", fmt.Errorf("no container runtime and '%s' not found on PATH: %w", binary, rtErr)
+	default:
+		if native {
+			return PathNative, "", nil
+		}
+		if rtErr == nil {
+			return PathContainer, rt, nil
+		}
+		return "", "", fmt.Errorf("'%s' not found on PATH and no container runtime available: %w", binary, rtErr)
+	}
+}
+
+// toolModeFor returns the per-tool policy: the configured override, else the
+// tool's own metadata.
+func toolModeFor(opts RunOpts) Mode {
+	if ToolMode != "" {
+		return ToolMode
+	}
+	return opts.Mode
+}
+
+// Plan reports where binary would run under the session and per-tool
+// policies and the executor backend, without running anything: PathNative,
+// PathContainer, or BackendSSH for the remote host. It backs
+// `coldcase check`.
+func Plan(sess *session.Session, binary string, toolMode Mode) (mode Mode, source, path string, err error) {
+	mode, source = effectiveMode(sess, toolMode)
+	if Backend == BackendSSH {
+		return mode, source, BackendSSH, remoteAllows(mode, source, binary)
+	}
+	path, _, err = route(mode, binary)
+	return mode, source, path, err
+}
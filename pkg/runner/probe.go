@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// versionProbeTimeout bounds how long a --version style probe may run.
+const versionProbeTimeout = 20 * time.Second
+
+// exitCode extracts the process exit status from a run error.
+// It returns 0 for success and -1 when the process never started.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	var remoteErr *ssh.ExitError
+	if errors.As(err, &remoteErr) {
+		return remoteErr.ExitStatus()
+	}
+	return -1
+}
+
+// nativeToolPath resolves binary to an absolute path with symlinks followed,
+// so the log names the exact file that ran.
+func nativeToolPath(binary string) string {
+	p, err := exec.LookPath(binary)
+	if err != nil {
+		return binary
+	}
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	if real, err := filepath.EvalSymlinks(p); err == nil {
+		p = real
+	}
+	return p
+}
+
+// ResolveImage resolves image to an immutable reference and its digest.
+// Images pulled from a registry resolve to repo@sha256:<manifest digest>;
+// locally built images have no registry digest and resolve to their image
+// ID, which the runtime accepts in place of a name.
+func ResolveImage(runtime, image string) (ref, digest string, err error) {
+	if i := strings.Index(image, "@sha256:"); i >= 0 {
+		return image, image[i+1:], nil
+	}
+	out, err := exec.Command(runtime, "image", "inspect", "--format",
+		"{{.Id}}{{range .RepoDigests}} {{.}}{{end}}", image).Output()
+	if err != nil {
+		return "", "", fmt.Errorf("cannot pin image %s: not available locally (pull or build it first)", image)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return "", "", fmt.Errorf("cannot pin image %s: %s returned no image ID", image, runtime)
+	}
+	id := fields[0]
+	if !strings.HasPrefix(id, "sha256:") {
+		id = "sha256:" + id // podman prints the bare hex
+	}
+
+	// Prefer the repo digest of the repository that was asked for.
+	repoDigests := fields[1:]
+	repo := imageRepo(image)
+	for _, rd := range repoDigests {
+		if name, d, ok := strings.Cut(rd, "@"); ok && (name == repo || strings.HasSuffix(name, "/"+repo)) {
+			return rd, d, nil
+		}
+	}
+	if len(repoDigests) > 0 {
+		if _, d, ok := strings.Cut(repoDigests[0], "@"); ok {
+			return repoDigests[0], d, nil
+		}
+	}
+	return id, id, nil
+}
+
+// imageRepo strips the tag from an image reference ("coldcase:latest" ->
+// "coldcase"), leaving registry ports such as "host:5000/x" intact.
+func imageRepo(image string) string {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i]
+	}
+	return image
+}
+
+// containerToolPath identifies a container-executed tool by image and digest.
+func containerToolPath(image, digest, binary string) string {
+	if digest == "" {
+		return image + ":" + binary
+	}
+	return image + "@" + digest + ":" + binary
+}
+
+// versionArgs returns the arguments used to ask opts.Binary for its version.
+func versionArgs(opts RunOpts) []string {
+	if len(opts.VersionArgs) > 0 {
+		return opts.VersionArgs
+	}
+	return []string{"--version"}
+}
+
+// versionKey identifies a tool build for the per-session version cache.
+// Path-like probe arguments (e.g. a script) are made absolute so the same
+// script run from different directories shares one entry.
+func versionKey(toolPath string, opts RunOpts) string {
+	parts := []string{toolPath}
+	for _, a := range versionArgs(opts) {
+		if pathExists(a) {
+			if abs, err := filepath.Abs(a); err == nil {
+				a = abs
+			}
+		}
+		parts = append(parts, a)
+	}
+	return strings.Join(parts, " ")
+}
+
+// probeVersion runs the tool's version probe natively or in the container
+// and returns the first non-empty line of its output. ok is false when the
+// probe failed, in which case the result should not be cached.
+func probeVersion(opts RunOpts, runtime string) (version string, ok bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), versionProbeTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime == "" {
+		cmd = exec.CommandContext(ctx, opts.Binary, versionArgs(opts)...)
+	} else {
+		mounts, remapped := detectMounts(versionArgs(opts), argSpec{})
+		args := append([]string{"run", "--rm"}, isolationArgs(containerIsolation(RunOpts{}))...)
+		for _, m := range mounts {
+			args = append(args, "-v", m)
+		}
+		args = append(args, imageFor(opts), opts.Binary)
+		args = append(args, remapped...)
+		cmd = exec.CommandContext(ctx, runtime, args...)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "unknown", false
+	}
+	for _, line := range strings.Split(string(bytes.TrimSpace(out)), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line, true
+		}
+	}
+	return "unknown", true
+}
@@ -4,13 +4,16 @@
 package runner
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
 	"time"
 
 	"coldcase/pkg/session"
-	"coldcase/pkg/tools"
 )
 
 const (
@@ -22,6 +25,13 @@ const (
 	EnvRuntime = "COLDCASE_RUNTIME"
 )
 
+// Image and Runtime, when set, take precedence over COLDCASE_IMAGE and
+// COLDCASE_RUNTIME. The CLI sets them from the resolved configuration.
+var (
+	Image   string
+	Runtime string
+)
+
 // RunOpts controls how a tool is executed.
 type RunOpts struct {
 	// Binary is the name of the host binary to look up (e.g. "tshark").
@@ -29,44 +39,133 @@ type RunOpts struct {
 	// Args are the arguments to pass to the binary.
 	Args []string
 	// NeedsRoot signals the tool may need elevated privileges (e.g. tcpdump).
-	// When running in a container, NET_ADMIN capability is added.
+	// When running in a container, NET_ADMIN and NET_RAW are added back.
 	NeedsRoot bool
+	// Network opts the tool in to a container network; empty means none.
+	Network Network
 	// WorkDir is an optional working directory override.
 	WorkDir string
+	// VersionArgs are passed to Binary to print its version for the session
+	// log (default: "--version"). The first non-empty output line is recorded.
+	VersionArgs []string
+	// Context cancels the run when done; nil means context.Background().
+	Context context.Context
+	// Timeout limits the run time; zero falls back to DefaultTimeout.
+	Timeout time.Duration
+	// Outputs lists the flags whose values are paths the tool writes to.
+	// They are created on the host and mounted read-write in containers.
+	Outputs []OutputArg
+	// Embedded lists tool-specific option syntaxes that carry a path inside
+	// a larger value, so it can be remapped for container runs.
+	Embedded []EmbeddedPath
+	// Inputs lists flags whose values must be existing paths; the run is
+	// refused before starting when one is missing.
+	Inputs []string
+	// Image overrides the container image for this tool (default: ImageName()).
+	Image string
+	// Parse converts the captured stdout into a JSON document that is saved
+	// alongside the raw output in the session. It only runs inside a session.
+	Parse func(stdout []byte) ([]byte, error)
+	// Mode is the tool's own run-mode policy; empty defers to DefaultMode.
+	// ToolMode and the session policy take precedence.
+	Mode Mode
 }
 
-// Run executes opts.Binary with opts.Args.
-// Native execution is attempted first. If the binary is not found on PATH,
-// Run falls back to running the command inside a container.
+// Run executes opts.Binary with opts.Args through the selected Executor:
+// with the local backend, natively or inside a container as decided by the
+// effective run-mode policy (see effectiveMode). Under the default
+// prefer-native policy a binary missing from PATH falls back to the
+// container.
 func Run(opts RunOpts) error {
 	sID := session.GetActiveSessionID()
 	var logger *session.Logger
 	var sess *session.Session
+	var m *session.Manager
 
 	if sID != "" {
-		m := session.NewManager()
+		m = session.NewManager()
 		var err error
 		sess, err = m.Load(sID)
-		if err == nil {
-			if sess.State != session.StateUnlocked {
-				return fmt.Errorf("active session '%s' is %s and read-only", sID, sess.State)
-			}
-			logger = session.NewLogger(sess)
+		if err != nil {
+			return fmt.Errorf("cannot open active session '%s': %w", sID, err)
+		}
+		if sess.State != session.StateUnlocked {
+			return fmt.Errorf("active session '%s' is %s and read-only", sID, sess.State)
+		}
+		// Refuse now rather than run a command that cannot be logged.
+		if _, err := session.SigningKey(sess); err != nil {
+			return err
+		}
+		logger = session.NewLogger(sess)
+		logger.Progress = os.Stderr
+	}
+
+	args, evidenceIDs, err := resolveEvidence(m, sess, opts.Args)
+	if err != nil {
+		return err
+	}
+	opts.Args = args
+
+	exe, policy, err := selectExecutor(sess, opts)
+	if err != nil {
+		return err
+	}
+	if c, ok := exe.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	if missing := missingInputs(opts.Args, opts.Inputs); len(missing) > 0 {
+		return fmt.Errorf("input not found: %s", strings.Join(missing, ", "))
+	}
+	var driftWarnings []string
+	if sess != nil {
+		if driftWarnings, err = checkDrift(m, sess, opts); err != nil {
+			return err
+		}
+	}
+	if err := prepareOutputs(opts.Args, specFor(opts)); err != nil {
+		return err
+	}
+	job := &Job{Opts: opts, Logger: logger, SessionID: sID}
+	if err := exe.Prepare(job); err != nil {
+		return err
+	}
+
+	// Stream both outputs live; with a session, tee each into its own log file.
+	stdoutLog, stderrLog := &sink{}, &sink{}
+	var stdoutPath, stderrPath string
+	if logger != nil {
+		var err error
+		if stdoutLog.w, stdoutPath, err = logger.OutputWriter(opts.Binary, "stdout.txt"); err != nil {
+			return fmt.Errorf("cannot create session output file: %w", err)
+		}
+		if stderrLog.w, stderrPath, err = logger.OutputWriter(opts.Binary, "stderr.txt"); err != nil {
+			stdoutLog.Close()
+			return fmt.Errorf("cannot create session output file: %w", err)
 		}
 	}
+	preview := &prefixBuffer{limit: session.PreviewSize}
+	var captured *bytes.Buffer
+	stdout := io.MultiWriter(os.Stdout, stdoutLog, preview)
+	if logger != nil && opts.Parse != nil {
+		captured = &bytes.Buffer{}
+		stdout = io.MultiWriter(stdout, captured)
+	}
+	stderr := io.MultiWriter(os.Stderr, stderrLog)
+
+	ctx, stop := runContext(opts)
+	defer stop()
 
 	start := time.Now()
-	var runErr error
-	var output []byte
+	runErr := exe.Exec(ctx, job, stdout, stderr)
 
-	if tools.CheckToolInstalled(opts.Binary) {
-		output, runErr = runNative(opts)
-	} else {
-		rt, err := detectRuntime()
-		if err != nil {
-			return fmt.Errorf("'%s' not found on PATH and no container runtime available: %w", opts.Binary, err)
+	code := exitCode(runErr)
+	status, runErr := runStatus(ctx, runErr)
+
+	for _, l := range []*sink{stdoutLog, stderrLog} {
+		if err := l.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "[!] Could not save output to session: %v\n", err)
 		}
-		output, runErr = runInContainer(rt, opts)
 	}
 
 	if logger != nil && sess != nil {
@@ -74,42 +173,72 @@ func Run(opts RunOpts) error {
 
 		// Map input files
 		var inputFiles []session.FileMetadata
-		for _, arg := range opts.Args {
-			if _, err := os.Stat(arg); err == nil {
-				meta, err := logger.HashInputFile(arg)
-				if err == nil {
-					inputFiles = append(inputFiles, meta)
-				}
+		for _, p := range inputPaths(opts.Args, specFor(opts)) {
+			meta, err := logger.HashInputFile(p)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[!] Could not hash input %s: %v\n", p, err)
+				continue
+			}
+			inputFiles = append(inputFiles, meta)
+			if meta.Unreadable > 0 {
+				fmt.Fprintf(os.Stderr, "[!] %d file(s) under %s could not be hashed; see the errors in manifest %s\n", meta.Unreadable, p, meta.Manifest)
+			}
+			// Registered evidence was checked before the run; this catches
+			// other inputs whose content changed since an earlier command.
+			if meta.PrevSHA256 != "" && meta.EvidenceID == "" {
+				w := fmt.Sprintf("%s changed since it was last hashed in this session: sha256 %s, previously %s", p, meta.SHA256, meta.PrevSHA256)
+				fmt.Fprintf(os.Stderr, "[!] INTEGRITY WARNING: %s\n", w)
+				driftWarnings = append(driftWarnings, w)
 			}
 		}
 
-		// Save output
-		idx := len(sess.Commands) + 1
-		outPath, _ := logger.SaveOutput(idx, opts.Binary, output)
+		// Probe the version once per tool build and cache it in the session.
+		key := versionKey(job.ToolPath, opts)
+		version, cached := logger.CachedToolVersion(key)
+		if !cached {
+			var ok bool
+			if version, ok = exe.Version(job); ok {
+				logger.CacheToolVersion(key, version)
+			}
+		}
 
-		preview := ""
-		if len(output) > 500 {
-			preview = string(output[:500]) + "..."
-		} else {
-			preview = string(output)
+		var parsedPath string
+		if captured != nil {
+			parsedPath = saveParsed(logger, opts, captured.Bytes())
 		}
 
 		wd, _ := os.Getwd()
 		entry := session.CommandEntry{
-			Index:            idx,
 			Timestamp:        start,
 			Command:          opts.Binary,
 			FullCommand:      fmt.Sprintf("%s %v", opts.Binary, opts.Args),
 			Args:             opts.Args,
 			InputFiles:       inputFiles,
+			ToolPath:         job.ToolPath,
+			ToolVersion:      version,
 			WorkingDirectory: wd,
-			ExitCode:         0, // Simplified for now
+			ExitCode:         code,
+			Status:           status,
 			DurationMS:       duration.Milliseconds(),
-			OutputPreview:    preview,
-			OutputFile:       outPath,
+			OutputPreview:    preview.Preview(),
+			OutputFile:       stdoutPath,
+			ErrorFile:        stderrPath,
+			ParsedFile:       parsedPath,
+			RunMode:          exe.Name(),
+			RunPolicy:        string(policy),
+			Image:            job.Image,
+			ImageDigest:      job.ImageDigest,
+			Isolation:        job.Isolation,
+			Remote:           job.Remote,
+			EvidenceIDs:      evidenceIDs,
+			DriftWarnings:    driftWarnings,
+		}
+		if idx, err := logger.LogCommand(entry); err != nil {
+			logger.Discard()
+			fmt.Fprintf(os.Stderr, "\n[!] Failed to log command to session %s: %v\n", sID, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "\n[*] Entry #%d logged to session: %s (%s, exit %d)\n", idx, sID, status, entry.ExitCode)
 		}
-		_ = logger.LogCommand(entry)
-		fmt.Fprintf(os.Stderr, "\n[*] Signed entry logged to session: %s\n", sID)
 	}
 
 	return runErr
@@ -128,8 +257,12 @@ func DetectedRuntime() string {
 	return rt
 }
 
-// ImageName returns the container image to use (respects COLDCASE_IMAGE env).
+// ImageName returns the container image to use (respects Image, then the
+// COLDCASE_IMAGE env).
 func ImageName() string {
+	if Image != "" {
+		return Image
+	}
 	if img := os.Getenv(EnvImage); img != "" {
 		return img
 	}
@@ -138,35 +271,100 @@ func ImageName() string {
 
 // ─── internal ─────────────────────────────────────────────────────────────────
 
-func runNative(opts RunOpts) ([]byte, error) {
-	cmd := exec.Command(opts.Binary, opts.Args...)
-	if opts.WorkDir != "" {
-		cmd.Dir = opts.WorkDir
+// imageFor returns the container image a tool runs in.
+func imageFor(opts RunOpts) string {
+	if opts.Image != "" {
+		return opts.Image
 	}
+	return ImageName()
+}
 
-	// Capture output while still showing it to the user
-	output, err := cmd.CombinedOutput()
-	fmt.Print(string(output))
-	return output, err
+// pinImage returns the reference a container run uses for image and its
+// digest. Inside a session the tag is resolved once, on first use, and every
+// later command reuses that pin; outside a session the tag is run as is.
+func pinImage(logger *session.Logger, sID, runtime, image string) (ref, digest string, err error) {
+	if logger == nil {
+		_, digest, _ = ResolveImage(runtime, image)
+		return image, digest, nil
+	}
+	if pin, ok := logger.PinnedImage(image); ok {
+		return pin.Ref, pin.Digest, nil
+	}
+	ref, digest, err = ResolveImage(runtime, image)
+	if err != nil {
+		return "", "", err
+	}
+	logger.PinImage(image, session.ImagePin{Ref: ref, Digest: digest, PinnedAt: time.Now()})
+	fmt.Fprintf(os.Stderr, "[*] Pinned %s to %s for session %s\n", image, ref, sID)
+	return ref, digest, nil
 }
 
-func runInContainer(runtime string, opts RunOpts) ([]byte, error) {
-	image := ImageName()
+// saveParsed runs the tool's output parser and stores the result in the
+// session, returning its path or "" when parsing failed.
+func saveParsed(logger *session.Logger, opts RunOpts, stdout []byte) string {
+	doc, err := opts.Parse(stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[!] Output parser failed: %v\n", err)
+		return ""
+	}
+	w, path, err := logger.OutputWriter(opts.Binary, "parsed.json")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[!] Could not save parsed output: %v\n", err)
+		return ""
+	}
+	_, err = w.Write(doc)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[!] Could not save parsed output: %v\n", err)
+		return ""
+	}
+	return path
+}
 
-	dockerArgs := []string{"run", "--rm", "-i"}
+// runNative runs the tool on the host, confined by sb when it is non-nil.
+func runNative(ctx context.Context, opts RunOpts, sb *sandboxSpec, stdout, stderr io.Writer) error {
+	var cmd *exec.Cmd
+	if sb == nil {
+		cmd = exec.CommandContext(ctx, opts.Binary, opts.Args...)
+		if opts.WorkDir != "" {
+			cmd.Dir = opts.WorkDir
+		}
+	} else {
+		var cleanup func()
+		var err error
+		if cmd, cleanup, err = sandboxedCommand(ctx, *sb); err != nil {
+			return err
+		}
+		defer cleanup()
+	}
+	bindNative(ctx, cmd)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+func runInContainer(ctx context.Context, runtime string, opts RunOpts, iso session.Isolation, stdout, stderr io.Writer) error {
+	image := imageFor(opts)
+	name := containerName()
 
-	// Attach a tty when stdin is a terminal.
+	dockerArgs := []string{"run", "--rm", "-i", "--name", name}
+
+	// Attach a tty when stdin is a terminal. Note that with a tty the runtime
+	// merges the tool's stderr into stdout.
 	if isTTY() {
 		dockerArgs = append(dockerArgs, "-t")
 	}
 
-	// Capability for network-level tools.
-	if opts.NeedsRoot {
-		dockerArgs = append(dockerArgs, "--cap-add", "NET_ADMIN", "--cap-add", "NET_RAW")
-	}
+	dockerArgs = append(dockerArgs, isolationArgs(iso)...)
 
 	// Auto-detect file paths in args and bind-mount them.
-	mounts, remapped := detectMounts(opts.Args)
+	if err := createOutputFiles(opts.Args, specFor(opts)); err != nil {
+		return err
+	}
+	mounts, remapped := detectMounts(opts.Args, specFor(opts))
 	for _, m := range mounts {
 		dockerArgs = append(dockerArgs, "-v", m)
 	}
@@ -179,14 +377,24 @@ func runInContainer(runtime string, opts RunOpts) ([]byte, error) {
 	dockerArgs = append(dockerArgs, image, opts.Binary)
 	dockerArgs = append(dockerArgs, remapped...)
 
-	cmd := exec.Command(runtime, dockerArgs...)
-	output, err := cmd.CombinedOutput()
-	fmt.Print(string(output))
-	return output, err
+	cmd := exec.CommandContext(ctx, runtime, dockerArgs...)
+	bindContainer(ctx, cmd, runtime, name)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	cleanupContainer(ctx, runtime, name)
+	return err
 }
 
 func detectRuntime() (string, error) {
 	// Respect explicit override.
+	if Runtime != "" {
+		if _, err := exec.LookPath(Runtime); err == nil {
+			return Runtime, nil
+		}
+		return "", fmt.Errorf("configured runtime %q not found on PATH", Runtime)
+	}
 	if rt := os.Getenv(EnvRuntime); rt != "" {
 		if _, err := exec.LookPath(rt); err == nil {
 			return rt, nil
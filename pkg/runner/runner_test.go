@@ -0,0 +1,245 @@
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"coldcase/pkg/session"
+)
+
+// inSession creates a session in a fresh base directory, makes it the
+// active one and installs fake as the executor.
+func inSession(t *testing.T, opts session.CreateOpts, fake *Fake) (*session.Manager, string) {
+	t.Helper()
+	prev := session.BaseDir()
+	session.SetBaseDir(t.TempDir())
+	t.Cleanup(func() { session.SetBaseDir(prev) })
+
+	m := session.NewManager()
+	s, err := m.Create("case", "tester", "", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("COLDCASE_SESSION_ID", s.ID)
+	t.Cleanup(SetExecutor(fake))
+	return m, s.ID
+}
+
+func loadCommands(t *testing.T, m *session.Manager, id string) []session.CommandEntry {
+	t.Helper()
+	s, err := m.Load(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Commands
+}
+
+func TestRunLogsFakeExecution(t *testing.T) {
+	fake := &Fake{Stdout: "hello\n"}
+	m, id := inSession(t, session.CreateOpts{}, fake)
+	dir := inTempDir(t, "dump.pcap")
+
+	err := Run(RunOpts{
+		Binary: "tshark",
+		Args:   []string{"-r", "dump.pcap"},
+		Inputs: []string{"-r"},
+		Parse:  func(stdout []byte) ([]byte, error) { return []byte(`{"lines":1}`), nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.Jobs) != 1 || fake.Jobs[0].SessionID != id {
+		t.Fatalf("jobs = %+v", fake.Jobs)
+	}
+
+	cmds := loadCommands(t, m, id)
+	if len(cmds) != 1 {
+		t.Fatalf("%d commands logged, want 1", len(cmds))
+	}
+	c := cmds[0]
+	if c.RunMode != BackendFake || c.ToolPath != "fake:tshark" || c.ToolVersion != "fake" {
+		t.Errorf("executor not recorded: run_mode %q, tool_path %q, version %q", c.RunMode, c.ToolPath, c.ToolVersion)
+	}
+	if c.Status != session.StatusCompleted || c.ExitCode != 0 || c.OutputPreview != "hello\n" {
+		t.Errorf("status %s, exit %d, preview %q", c.Status, c.ExitCode, c.OutputPreview)
+	}
+	sum := sha256.Sum256([]byte("x"))
+	if len(c.InputFiles) != 1 || c.InputFiles[0].Path != dir+"/dump.pcap" || c.InputFiles[0].SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("inputs = %+v", c.InputFiles)
+	}
+
+	s, _ := m.Load(id)
+	for _, f := range []struct{ rel, want string }{{c.OutputFile, "hello\n"}, {c.ParsedFile, `{"lines":1}`}} {
+		got, err := m.ReadOutput(s, f.rel)
+		if err != nil || string(got) != f.want {
+			t.Errorf("%s = %q, %v; want %q", f.rel, got, err, f.want)
+		}
+	}
+}
+
+func TestRunLogsFakeFailure(t *testing.T) {
+	fake := &Fake{Stderr: "no such file\n", Err: errors.New("boom")}
+	m, id := inSession(t, session.CreateOpts{}, fake)
+	inTempDir(t)
+
+	if err := Run(RunOpts{Binary: "xxd", Args: []string{"-l", "16"}}); err == nil || err.Error() != "boom" {
+		t.Fatalf("Run = %v, want the executor's error", err)
+	}
+	cmds := loadCommands(t, m, id)
+	if len(cmds) != 1 || cmds[0].Status != session.StatusFailed || cmds[0].ExitCode != -1 {
+		t.Fatalf("commands = %+v", cmds)
+	}
+	s, _ := m.Load(id)
+	if got, _ := m.ReadOutput(s, cmds[0].ErrorFile); string(got) != "no such file\n" {
+		t.Errorf("stderr = %q", got)
+	}
+}
+
+func TestRunRefusesBeforeExecuting(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		setup func(t *testing.T, m *session.Manager, id string)
+		opts  RunOpts
+		want  string
+	}{
+		{
+			name: "missing input",
+			opts: RunOpts{Binary: "tshark", Args: []string{"-r", "gone.pcap"}, Inputs: []string{"-r"}},
+			want: "input not found",
+		},
+		{
+			name: "output directory over evidence",
+			opts: RunOpts{Binary: "vol.py", Args: []string{"-f", "ev/mem.raw", "-o", "ev"}, Outputs: []OutputArg{{Flag: "-o", Dir: true}}},
+			want: "contains input",
+		},
+		{
+			name: "locked session",
+			setup: func(t *testing.T, m *session.Manager, id string) {
+				s, _ := m.Load(id)
+				if err := m.SetState(s, session.StateLocked); err != nil {
+					t.Fatal(err)
+				}
+			},
+			opts: RunOpts{Binary: "xxd", Args: []string{"ev/mem.raw"}},
+			want: "read-only",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &Fake{}
+			m, id := inSession(t, session.CreateOpts{}, fake)
+			inTempDir(t, "ev/mem.raw")
+			if tc.setup != nil {
+				tc.setup(t, m, id)
+			}
+			err := Run(tc.opts)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("Run = %v, want %q", err, tc.want)
+			}
+			if len(fake.Jobs) != 0 {
+				t.Error("executor ran")
+			}
+			if cmds := loadCommands(t, m, id); len(cmds) != 0 {
+				t.Errorf("%d commands logged", len(cmds))
+			}
+		})
+	}
+}
+
+func TestSelectExecutorHonoursRunMode(t *testing.T) {
+	prev := Backend
+	Backend = BackendSSH
+	t.Cleanup(func() { Backend = prev })
+
+	bound := &session.Session{RunMode: string(ModeContainerOnly)}
+	if _, _, err := selectExecutor(bound, RunOpts{Binary: "xxd"}); err == nil {
+		t.Error("ssh executor accepted a container-only session")
+	}
+	if _, _, err := selectExecutor(nil, RunOpts{Binary: "xxd", Mode: ModeContainerOnly}); err == nil {
+		t.Error("ssh executor accepted a container-only tool")
+	}
+	e, policy, err := selectExecutor(&session.Session{RunMode: string(ModeNativeOnly)}, RunOpts{Binary: "xxd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e.(*SSH); !ok || policy != ModeNativeOnly {
+		t.Errorf("got %T under %q, want *SSH under native-only", e, policy)
+	}
+}
+
+func TestFakeIsNotASetting(t *testing.T) {
+	if _, err := ParseBackend(BackendFake); err == nil {
+		t.Fatal("executor setting accepted fake")
+	}
+}
+
+func TestPlanMatchesSSHSelection(t *testing.T) {
+	prev := Backend
+	Backend = BackendSSH
+	t.Cleanup(func() { Backend = prev })
+
+	bound := &session.Session{ID: "case", RunMode: string(ModeContainerOnly)}
+	if mode, source, _, err := Plan(bound, "xxd", ""); err == nil || mode != ModeContainerOnly || source != "session" {
+		t.Errorf("Plan under a container-only session = %s (%s), %v; want blocked", mode, source, err)
+	}
+	if _, _, path, err := Plan(nil, "xxd", ModePreferNative); err != nil || path != BackendSSH {
+		t.Errorf("Plan = %q, %v; want %s", path, err, BackendSSH)
+	}
+}
+
+// midRunFake moves the session to another state while the command runs,
+// as a second process would.
+type midRunFake struct {
+	*Fake
+	during func()
+}
+
+func (f *midRunFake) Exec(ctx context.Context, job *Job, stdout, stderr io.Writer) error {
+	io.WriteString(stdout, "partial\n")
+	f.during()
+	return f.Fake.Exec(ctx, job, stdout, stderr)
+}
+
+func TestRunDiscardsOutputsWhenSessionClosesMidRun(t *testing.T) {
+	for _, to := range []session.State{session.StateSealed, session.StateLocked} {
+		t.Run(string(to), func(t *testing.T) {
+			fake := &midRunFake{Fake: &Fake{Stdout: "done\n"}}
+			m, id := inSession(t, session.CreateOpts{}, fake.Fake)
+			t.Cleanup(SetExecutor(fake))
+			fake.during = func() {
+				s, _ := m.Load(id)
+				if err := m.SetState(s, to); err != nil {
+					t.Error(err)
+				}
+			}
+			inTempDir(t)
+
+			if err := Run(RunOpts{Binary: "xxd", Args: []string{"-l", "16"}}); err != nil {
+				t.Fatal(err)
+			}
+			if cmds := loadCommands(t, m, id); len(cmds) != 0 {
+				t.Fatalf("%d commands logged into a %s session", len(cmds), to)
+			}
+			left, _ := filepath.Glob(filepath.Join(session.BaseDir(), "sessions", id, "outputs", "*"))
+			if len(left) != 0 {
+				t.Errorf("outputs left behind: %v", left)
+			}
+			if to != session.StateSealed {
+				return
+			}
+			s, _ := m.Load(id)
+			report, err := m.VerifySeal(s, nil)
+			if err != nil || !report.Intact() {
+				t.Errorf("VerifySeal = %+v, %v", report, err)
+			}
+			if len(s.SealedOutputs) != 0 {
+				t.Errorf("seal covers the running command's outputs: %+v", s.SealedOutputs)
+			}
+		})
+	}
+}
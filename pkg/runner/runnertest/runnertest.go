@@ -0,0 +1,79 @@
+// Package runnertest runs tool wrappers against runner.Fake, so tool
+// packages can test the RunOpts they build with their real argument specs.
+package runnertest
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"coldcase/pkg/runner"
+)
+
+// Run creates files (relative paths) in a fresh directory, makes it the
+// working directory and calls run outside any session with a Fake
+// executor installed. It returns the directory and the options of the one
+// job run started.
+func Run(t *testing.T, files []string, run func() error) (dir string, opts runner.RunOpts) {
+	t.Helper()
+	dir = t.TempDir()
+	for _, f := range files {
+		p := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+	t.Setenv("COLDCASE_SESSION_ID", "")
+
+	fake := &runner.Fake{}
+	defer runner.SetExecutor(fake)()
+	if err := run(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(fake.Jobs) != 1 {
+		t.Fatalf("%d jobs run, want 1", len(fake.Jobs))
+	}
+	return dir, fake.Jobs[0].Opts
+}
+
+// Case is one invocation of a tool and the container view wanted for it.
+// "$D" in Want and Mounts stands for the scratch directory.
+type Case struct {
+	Name   string
+	Files  []string
+	Args   []string
+	Want   []string // rewritten argv, as the tool receives it
+	Mounts []string
+}
+
+// Check runs each case through run and compares runner.ContainerMounts of
+// the resulting job with the case.
+func Check(t *testing.T, cases []Case, run func(args []string) error) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			dir, opts := Run(t, tc.Files, func() error { return run(tc.Args) })
+			mounts, args := runner.ContainerMounts(opts)
+			if want := expand(dir, tc.Want); !slices.Equal(args, want) {
+				t.Errorf("argv:\n got  %q\n want %q", args, want)
+			}
+			if want := expand(dir, tc.Mounts); !slices.Equal(mounts, want) {
+				t.Errorf("mounts:\n got  %q\n want %q", mounts, want)
+			}
+		})
+	}
+}
+
+func expand(dir string, ss []string) []string {
+	var out []string
+	for _, s := range ss {
+		out = append(out, strings.ReplaceAll(s, "$D", dir))
+	}
+	return out
+}
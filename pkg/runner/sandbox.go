@@ -0,0 +1,234 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"coldcase/pkg/session"
+)
+
+// Sandbox selects how native runs are confined on Linux.
+type Sandbox string
+
+const (
+	SandboxOff    Sandbox = "off"    // run with the analyst's full privileges (default)
+	SandboxAuto   Sandbox = "auto"   // bwrap when installed, else the built-in user-namespace sandbox
+	SandboxBwrap  Sandbox = "bwrap"  // bubblewrap
+	SandboxUserNS Sandbox = "userns" // built-in user-namespace sandbox
+)
+
+// Sandboxes lists the valid sandbox settings.
+var Sandboxes = []Sandbox{SandboxOff, SandboxAuto, SandboxBwrap, SandboxUserNS}
+
+// NativeSandbox confines every native run. The CLI sets it from the
+// resolved "sandbox" setting.
+var NativeSandbox = SandboxOff
+
+// ParseSandbox validates a sandbox setting; the empty string means SandboxOff.
+func ParseSandbox(s string) (Sandbox, error) {
+	if s == "" {
+		return SandboxOff, nil
+	}
+	for _, sb := range Sandboxes {
+		if Sandbox(s) == sb {
+			return sb, nil
+		}
+	}
+	names := make([]string, len(Sandboxes))
+	for i, sb := range Sandboxes {
+		names[i] = string(sb)
+	}
+	return "", fmt.Errorf("unknown sandbox %q (valid: %s)", s, strings.Join(names, ", "))
+}
+
+// systemDirs are exposed read-only in every sandbox so tools and their
+// interpreters and libraries can load. Symlinked entries (e.g. /bin on
+// merged-/usr systems) are recreated as symlinks.
+var systemDirs = []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/libx32", "/etc", "/opt"}
+
+// sandboxBind is one host path exposed inside the sandbox at the same path.
+type sandboxBind struct {
+	Path     string
+	Writable bool
+}
+
+// sandboxSpec is everything needed to start a tool in a sandbox. It is
+// passed to the re-executed helper of the user-namespace backend as JSON.
+type sandboxSpec struct {
+	Kind   Sandbox
+	Binary string
+	// Args are the tool arguments with every path made absolute, since the
+	// working directory inside the sandbox starts out empty.
+	Args []string
+	// Binds are ordered shortest path first so nested binds take effect.
+	Binds []sandboxBind
+	Dir   string
+	// DirExposed is false when the working directory is not one of the binds;
+	// it is then an empty read-only directory, so writes to undeclared
+	// outputs fail instead of vanishing with the sandbox.
+	DirExposed bool
+	Network    bool
+	// Root is the host directory the user-namespace backend mounts the new
+	// root on; unused by bwrap.
+	Root string `json:",omitempty"`
+}
+
+// resolveSandbox turns the configured sandbox into a concrete backend, or
+// SandboxOff when native runs are not confined.
+func resolveSandbox() (Sandbox, error) {
+	switch NativeSandbox {
+	case SandboxOff, "":
+		return SandboxOff, nil
+	case SandboxAuto:
+		if _, err := exec.LookPath("bwrap"); err == nil {
+			return SandboxBwrap, nil
+		}
+		return SandboxUserNS, nil
+	case SandboxBwrap:
+		if _, err := exec.LookPath("bwrap"); err != nil {
+			return "", fmt.Errorf("sandbox %q: bwrap not found on PATH", NativeSandbox)
+		}
+	}
+	return NativeSandbox, nil
+}
+
+// buildSandboxSpec exposes the tool binary, its input paths read-only and
+// its output directories read-write; nothing else of the host filesystem
+// beyond systemDirs is visible.
+func buildSandboxSpec(kind Sandbox, opts RunOpts) (sandboxSpec, error) {
+	bin, err := exec.LookPath(opts.Binary)
+	if err != nil {
+		return sandboxSpec{}, err
+	}
+	if bin, err = filepath.Abs(bin); err != nil {
+		return sandboxSpec{}, err
+	}
+	dir := opts.WorkDir
+	if dir == "" {
+		if dir, err = os.Getwd(); err != nil {
+			return sandboxSpec{}, err
+		}
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return sandboxSpec{}, err
+	}
+
+	binds := map[string]bool{} // path -> writable
+	expose := func(p string, writable bool) {
+		binds[p] = binds[p] || writable
+	}
+	// The binary and its symlink target, unless a system directory has them.
+	for _, p := range []string{bin, nativeToolPath(bin)} {
+		if !underSystemDir(p) {
+			expose(filepath.Dir(p), false)
+		}
+	}
+
+	args := rewriteArgs(opts.Args, specFor(opts), func(r pathRef) string {
+		abs := r.Host
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(dir, abs)
+		}
+		switch {
+		case r.Output && r.Dir:
+			expose(abs, true)
+		case r.Output:
+			expose(filepath.Dir(abs), true)
+		default:
+			expose(abs, false)
+		}
+		return abs
+	})
+
+	spec := sandboxSpec{
+		Kind:    kind,
+		Binary:  bin,
+		Args:    args,
+		Dir:     dir,
+		Network: opts.Network != "" && opts.Network != NetworkNone,
+	}
+	for p, w := range binds {
+		spec.Binds = append(spec.Binds, sandboxBind{p, w})
+	}
+	sort.Slice(spec.Binds, func(i, j int) bool {
+		a, b := spec.Binds[i].Path, spec.Binds[j].Path
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+	for _, b := range spec.Binds {
+		if dir == b.Path || strings.HasPrefix(dir, b.Path+"/") {
+			spec.DirExposed = true
+		}
+	}
+	return spec, nil
+}
+
+func underSystemDir(p string) bool {
+	for _, d := range systemDirs {
+		if p == d || strings.HasPrefix(p, d+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isolation describes the sandboxed run for the session log.
+func (spec sandboxSpec) isolation() session.Isolation {
+	iso := session.Isolation{
+		Sandbox:      string(spec.Kind),
+		Network:      string(NetworkNone),
+		ReadOnlyRoot: true,
+		Tmpfs:        []string{"/tmp"},
+	}
+	if spec.Network {
+		iso.Network = string(NetworkHost)
+	}
+	return iso
+}
+
+// bwrapArgs renders spec as a bubblewrap command line.
+func bwrapArgs(spec sandboxSpec) []string {
+	args := []string{"--unshare-all", "--die-with-parent"}
+	if spec.Network {
+		args = append(args, "--share-net")
+	}
+	for _, d := range systemDirs {
+		if target, err := os.Readlink(d); err == nil {
+			args = append(args, "--symlink", target, d)
+		} else {
+			args = append(args, "--ro-bind-try", d, d)
+		}
+	}
+	args = append(args, "--proc", "/proc", "--dev", "/dev", "--tmpfs", "/tmp", "--setenv", "HOME", "/tmp")
+	if !spec.DirExposed {
+		args = append(args, "--tmpfs", spec.Dir)
+	}
+	for _, b := range spec.Binds {
+		if b.Writable {
+			args = append(args, "--bind", b.Path, b.Path)
+		} else {
+			args = append(args, "--ro-bind-try", b.Path, b.Path)
+		}
+	}
+	if !spec.DirExposed {
+		args = append(args, "--remount-ro", spec.Dir)
+	}
+	args = append(args, "--remount-ro", "/", "--chdir", spec.Dir, "--", spec.Binary)
+	return append(args, spec.Args...)
+}
+
+// sandboxedCommand returns the command that runs spec, and a cleanup
+// function to call once it has exited.
+func sandboxedCommand(ctx context.Context, spec sandboxSpec) (*exec.Cmd, func(), error) {
+	if spec.Kind == SandboxBwrap {
+		return exec.CommandContext(ctx, "bwrap", bwrapArgs(spec)...), func() {}, nil
+	}
+	return userNSCommand(ctx, spec)
+}
@@ -0,0 +1,264 @@
+//go:build linux
+
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// sandboxEnv carries the JSON sandboxSpec to the re-executed helper.
+const sandboxEnv = "COLDCASE_SANDBOX_SPEC"
+
+// The user-namespace backend re-executes ColdCase in fresh user, mount, PID,
+// IPC, UTS and network namespaces. This init runs in that child before main:
+// it builds the new root from the spec, starts the tool and stays behind as
+// the namespace's init, so it never returns when the variable is set.
+func init() {
+	data := os.Getenv(sandboxEnv)
+	if data == "" {
+		return
+	}
+	os.Unsetenv(sandboxEnv)
+	var spec sandboxSpec
+	err := json.Unmarshal([]byte(data), &spec)
+	if err == nil {
+		err = enterSandbox(spec)
+	}
+	fmt.Fprintf(os.Stderr, "coldcase sandbox: %v\n", err)
+	os.Exit(126)
+}
+
+// userNSCommand starts the helper that runs spec in new namespaces. The
+// caller's uid and gid are mapped to themselves, so files the tool creates
+// in output directories keep the analyst's ownership.
+func userNSCommand(ctx context.Context, spec sandboxSpec) (*exec.Cmd, func(), error) {
+	root, err := os.MkdirTemp("", "coldcase-sandbox-")
+	if err != nil {
+		return nil, nil, err
+	}
+	spec.Root = root
+	data, err := json.Marshal(spec)
+	if err != nil {
+		os.Remove(root)
+		return nil, nil, err
+	}
+
+	flags := syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	if !spec.Network {
+		flags |= syscall.CLONE_NEWNET
+	}
+	cmd := exec.CommandContext(ctx, "/proc/self/exe")
+	cmd.Env = append(os.Environ(), sandboxEnv+"="+string(data))
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:                 uintptr(flags),
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}},
+		GidMappingsEnableSetgroups: false,
+	}
+	return cmd, func() { os.Remove(root) }, nil
+}
+
+// enterSandbox builds the new root on spec.Root, pivots into it and execs
+// the tool. It only returns on error.
+func enterSandbox(spec sandboxSpec) error {
+	root := spec.Root
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("making mounts private: %w", err)
+	}
+	if err := syscall.Mount("tmpfs", root, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=0755"); err != nil {
+		return fmt.Errorf("mounting sandbox root: %w", err)
+	}
+
+	for _, d := range systemDirs {
+		if target, err := os.Readlink(d); err == nil {
+			if err := os.Symlink(target, root+d); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := bindMount(d, root+d, false); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Mkdir(root+"/proc", 0555); err != nil {
+		return err
+	}
+	if err := syscall.Mount("proc", root+"/proc", "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mounting /proc: %w", err)
+	}
+	if err := os.Mkdir(root+"/dev", 0755); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", root+"/dev", "tmpfs", syscall.MS_NOSUID|syscall.MS_NOEXEC, "mode=0755"); err != nil {
+		return fmt.Errorf("mounting /dev: %w", err)
+	}
+	for _, dev := range []string{"null", "zero", "full", "random", "urandom", "tty"} {
+		// /dev/tty is absent without a controlling terminal; that is fine.
+		_ = bindMount("/dev/"+dev, root+"/dev/"+dev, true)
+	}
+	if err := os.Mkdir(root+"/tmp", 0777); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", root+"/tmp", "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("mounting /tmp: %w", err)
+	}
+
+	// An unexposed working directory gets its own tmpfs, mounted before the
+	// binds so inputs beneath it stay visible, and made read-only after.
+	if !spec.DirExposed {
+		if err := os.MkdirAll(root+spec.Dir, 0755); err != nil {
+			return err
+		}
+		if err := syscall.Mount("tmpfs", root+spec.Dir, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=0755"); err != nil {
+			return fmt.Errorf("mounting working directory: %w", err)
+		}
+	}
+	for _, b := range spec.Binds {
+		if err := bindMount(b.Path, root+b.Path, b.Writable); err != nil {
+			return err
+		}
+	}
+	if !spec.DirExposed {
+		if err := syscall.Mount("", root+spec.Dir, "", syscall.MS_REMOUNT|syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV, ""); err != nil {
+			return fmt.Errorf("making working directory read-only: %w", err)
+		}
+	}
+	if err := syscall.Mount("", root, "", syscall.MS_REMOUNT|syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("making sandbox root read-only: %w", err)
+	}
+
+	if err := syscall.Chdir(root); err != nil {
+		return err
+	}
+	if err := syscall.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("pivot_root: %w", err)
+	}
+	if err := syscall.Unmount(".", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("detaching host root: %w", err)
+	}
+	if err := syscall.Chdir(spec.Dir); err != nil {
+		return err
+	}
+
+	env := []string{"HOME=/tmp"}
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, "HOME=") {
+			env = append(env, e)
+		}
+	}
+	return runInit(spec.Binary, spec.Args, env)
+}
+
+// forwardedSignals are passed on from the sandbox init to the tool.
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGUSR1, syscall.SIGUSR2}
+
+// runInit runs the tool as a child of the helper, which is PID 1 of the new
+// PID namespace. The kernel drops signals to PID 1 that it has no handler
+// for, so a tool exec'd in its place would ignore SIGINT and SIGTERM from
+// the runner. Instead the helper forwards those signals, reaps orphaned
+// grandchildren, and exits with the tool's status (128+n when it was killed
+// by signal n). Its exit tears down whatever the tool left running.
+func runInit(binary string, args, env []string) error {
+	sigs := make(chan os.Signal, len(forwardedSignals))
+	signal.Notify(sigs, forwardedSignals...)
+	p, err := os.StartProcess(binary, append([]string{binary}, args...), &os.ProcAttr{
+		Env:   env,
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		for sig := range sigs {
+			syscall.Kill(p.Pid, sig.(syscall.Signal))
+		}
+	}()
+
+	for {
+		var ws syscall.WaitStatus
+		pid, err := syscall.Wait4(-1, &ws, 0, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("waiting for %s: %w", binary, err)
+		}
+		if pid != p.Pid {
+			continue
+		}
+		if ws.Signaled() {
+			os.Exit(128 + int(ws.Signal()))
+		}
+		os.Exit(ws.ExitStatus())
+	}
+}
+
+// bindMount exposes src at dst, read-only unless writable. Missing sources
+// are skipped so the tool reports them itself.
+func bindMount(src, dst string, writable bool) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil
+	}
+	if info.IsDir() {
+		err = os.MkdirAll(dst, 0755)
+	} else if _, serr := os.Lstat(dst); serr != nil {
+		if err = os.MkdirAll(filepath.Dir(dst), 0755); err == nil {
+			var f *os.File
+			if f, err = os.Create(dst); err == nil {
+				err = f.Close()
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("preparing mount point for %s: %w", src, err)
+	}
+	if err := syscall.Mount(src, dst, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("binding %s: %w", src, err)
+	}
+	if writable {
+		return nil
+	}
+	// A remount inside a user namespace must keep the flags locked by the
+	// parent mount (nosuid, nodev, ...), so carry them over.
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dst, &st); err != nil {
+		return err
+	}
+	flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY)
+	for _, f := range []struct{ st, ms int64 }{
+		{stNosuid, syscall.MS_NOSUID},
+		{stNodev, syscall.MS_NODEV},
+		{stNoexec, syscall.MS_NOEXEC},
+		{stNoatime, syscall.MS_NOATIME},
+		{stNodiratime, syscall.MS_NODIRATIME},
+		{stRelatime, syscall.MS_RELATIME},
+	} {
+		if int64(st.Flags)&f.st != 0 {
+			flags |= uintptr(f.ms)
+		}
+	}
+	if err := syscall.Mount("", dst, "", flags, ""); err != nil {
+		return fmt.Errorf("making %s read-only: %w", src, err)
+	}
+	return nil
+}
+
+// statfs f_flags bits (ST_* in <sys/statvfs.h>).
+const (
+	stNosuid     = 0x2
+	stNodev      = 0x4
+	stNoexec     = 0x8
+	stNoatime    = 0x400
+	stNodiratime = 0x800
+	stRelatime   = 0x1000
+)
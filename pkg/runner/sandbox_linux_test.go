@@ -0,0 +1,61 @@
+//go:build linux
+
+package runner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestUserNSSandboxForwardsSignals checks that a tool without a SIGTERM
+// handler still dies when the runner cancels it: the sandbox init, not the
+// tool, is PID 1 of the namespace.
+func TestUserNSSandboxForwardsSignals(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	spec := sandboxSpec{
+		Kind:   SandboxUserNS,
+		Binary: sh,
+		Args:   []string{"-c", "echo ready; exec sleep 30"},
+		Dir:    t.TempDir(),
+	}
+	cmd, cleanup, err := userNSCommand(context.Background(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("user namespaces unavailable: %v", err)
+	}
+	line, err := bufio.NewReader(out).ReadString('\n')
+	if line != "ready\n" {
+		cmd.Process.Kill()
+		cmd.Wait()
+		t.Skipf("sandbox did not start the tool (%q, %v)", line, err)
+	}
+
+	cmd.Process.Signal(syscall.SIGTERM)
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		var exit *exec.ExitError
+		if !errors.As(err, &exit) || exit.ExitCode() != 128+int(syscall.SIGTERM) {
+			t.Fatalf("tool exit = %v, want status %d", err, 128+int(syscall.SIGTERM))
+		}
+	case <-time.After(10 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("SIGTERM was not delivered to the sandboxed tool")
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
+// userNSCommand is only available on Linux.
+func userNSCommand(ctx context.Context, spec sandboxSpec) (*exec.Cmd, func(), error) {
+	return nil, nil, errors.New("the native sandbox requires Linux user namespaces")
+}
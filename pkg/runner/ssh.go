@@ -0,0 +1,358 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"coldcase/pkg/session"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// BackendSSH runs tools on a remote forensic workstation over SSH.
+const BackendSSH = "ssh"
+
+// sshDialTimeout bounds connecting and authenticating to the remote host.
+const sshDialTimeout = 15 * time.Second
+
+// RemoteConfig configures the ssh executor.
+type RemoteConfig struct {
+	// Host is [user@]host[:port]; the user defaults to the local user.
+	Host string
+	// Identity is a private key file. Empty tries ssh-agent and the
+	// default ~/.ssh/id_ed25519, id_ecdsa and id_rsa keys.
+	Identity string
+	// KnownHosts verifies the server's host key (default ~/.ssh/known_hosts).
+	KnownHosts string
+	// Paths maps local paths to the server's evidence store. Every path
+	// argument must fall under one of them: evidence is never uploaded.
+	Paths []PathMap
+}
+
+// PathMap maps a local path prefix to the same data on the remote host,
+// typically a local mount of the server's evidence share.
+type PathMap struct {
+	Local  string
+	Remote string
+}
+
+// Remote is the ssh executor configuration. The CLI sets it from the
+// remote_* settings.
+var Remote RemoteConfig
+
+// ParsePathMaps parses "local=remote" pairs separated by commas, e.g.
+// "/mnt/lab/evidence=/srv/evidence,/mnt/lab/cases=/srv/cases".
+func ParsePathMaps(s string) ([]PathMap, error) {
+	var maps []PathMap
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		local, remote, ok := strings.Cut(pair, "=")
+		if !ok || !filepath.IsAbs(local) || !strings.HasPrefix(remote, "/") {
+			return nil, fmt.Errorf("invalid path mapping %q (want /local/prefix=/remote/prefix)", pair)
+		}
+		maps = append(maps, PathMap{filepath.Clean(local), strings.TrimSuffix(remote, "/")})
+	}
+	return maps, nil
+}
+
+// toRemote maps an absolute local path onto the remote host. The longest
+// matching prefix wins.
+func (c RemoteConfig) toRemote(local string) (string, bool) {
+	best, found := "", false
+	bestLen := -1
+	for _, m := range c.Paths {
+		if local == m.Local || strings.HasPrefix(local, m.Local+"/") {
+			if len(m.Local) > bestLen {
+				best, bestLen, found = m.Remote+strings.TrimPrefix(local, m.Local), len(m.Local), true
+			}
+		}
+	}
+	return best, found
+}
+
+// SSH runs tools on Remote.Host. Path arguments are translated through
+// Remote.Paths; output is streamed back as the tool produces it.
+type SSH struct {
+	client *ssh.Client
+	// agent is the connection to ssh-agent, when its keys were offered.
+	agent  net.Conn
+	remote session.RemoteExec
+	args   []string
+	dir    string
+}
+
+func (s *SSH) Name() string { return BackendSSH }
+
+// Prepare maps the arguments, connects, verifies the host key and resolves
+// the tool on the server.
+func (s *SSH) Prepare(job *Job) error {
+	var unmapped []string
+	s.args, unmapped = mapRemoteArgs(job.Opts.Args, specFor(job.Opts))
+	if len(unmapped) > 0 {
+		return fmt.Errorf("not under a remote path mapping (evidence is not uploaded): %s", strings.Join(unmapped, ", "))
+	}
+	dir := job.Opts.WorkDir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		s.dir, _ = Remote.toRemote(abs)
+	}
+
+	if err := s.connect(); err != nil {
+		return err
+	}
+	bin := job.Opts.Binary
+	if out, err := s.output("command -v " + shellQuote(bin)); err == nil {
+		if p := strings.TrimSpace(string(out)); p != "" {
+			bin = p
+		}
+	} else {
+		return fmt.Errorf("'%s' not found on %s", job.Opts.Binary, s.remote.Host)
+	}
+
+	s.remote.Command = s.commandLine(job.Opts.Binary, s.args)
+	job.ToolPath = "ssh://" + s.remote.User + "@" + s.remote.Host + bin
+	job.Remote = &s.remote
+	return nil
+}
+
+func (s *SSH) Exec(ctx context.Context, job *Job, stdout, stderr io.Writer) error {
+	sess, err := s.client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+	sess.Stdout = stdout
+	sess.Stderr = stderr
+	if err := sess.Start(s.remote.Command); err != nil {
+		return err
+	}
+
+	// Forward cancellation as a signal, then drop the session after the
+	// grace period if the tool ignores it.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = sess.Signal(sshSignal(forwardSignal(ctx)))
+			select {
+			case <-time.After(killGrace):
+				sess.Close()
+			case <-done:
+			}
+		case <-done:
+		}
+	}()
+	return sess.Wait()
+}
+
+func (s *SSH) Version(job *Job) (string, bool) {
+	args, _ := mapRemoteArgs(versionArgs(job.Opts), argSpec{})
+	out, err := s.output(s.commandLine(job.Opts.Binary, args) + " 2>&1")
+	if err != nil && len(out) == 0 {
+		return "unknown", false
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line, true
+		}
+	}
+	return "unknown", true
+}
+
+// Close disconnects from the remote host and from ssh-agent.
+func (s *SSH) Close() error {
+	var err error
+	if s.client != nil {
+		err = s.client.Close()
+	}
+	if s.agent != nil {
+		if aerr := s.agent.Close(); err == nil {
+			err = aerr
+		}
+	}
+	return err
+}
+
+// mapRemoteArgs translates every path in args through Remote.Paths and
+// returns the paths that no mapping covers.
+func mapRemoteArgs(args []string, spec argSpec) (mapped, unmapped []string) {
+	mapped = rewriteArgs(args, spec, func(r pathRef) string {
+		abs, err := filepath.Abs(r.Host)
+		if err != nil {
+			abs = r.Host
+		}
+		remote, ok := Remote.toRemote(abs)
+		if !ok {
+			unmapped = append(unmapped, abs)
+			return r.Host
+		}
+		return remote
+	})
+	return mapped, unmapped
+}
+
+// commandLine quotes binary and args for the remote shell, running in the
+// mapped working directory when there is one.
+func (s *SSH) commandLine(binary string, args []string) string {
+	parts := []string{shellQuote(binary)}
+	for _, a := range args {
+		parts = append(parts, shellQuote(a))
+	}
+	line := "exec " + strings.Join(parts, " ")
+	if s.dir != "" {
+		line = "cd " + shellQuote(s.dir) + " && " + line
+	}
+	return line
+}
+
+// output runs a short command and returns its stdout.
+func (s *SSH) output(cmd string) ([]byte, error) {
+	sess, err := s.client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Close()
+	return sess.Output(cmd)
+}
+
+// connect dials Remote.Host, accepting only a host key listed in known_hosts.
+func (s *SSH) connect() error {
+	if Remote.Host == "" {
+		return errors.New("executor is ssh but remote_host is not set")
+	}
+	userName, addr := splitRemoteHost(Remote.Host)
+
+	khPath := Remote.KnownHosts
+	if khPath == "" {
+		home, _ := os.UserHomeDir()
+		khPath = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	check, err := knownhosts.New(khPath)
+	if err != nil {
+		return fmt.Errorf("cannot read known hosts %s: %w", khPath, err)
+	}
+	hostKey := func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if err := check(hostname, remote, key); err != nil {
+			return fmt.Errorf("host key %s for %s not trusted by %s: %w", ssh.FingerprintSHA256(key), hostname, khPath, err)
+		}
+		s.remote.HostKey = ssh.FingerprintSHA256(key)
+		s.remote.HostKeyType = key.Type()
+		return nil
+	}
+
+	auth, agentConn, err := sshAuth()
+	if err != nil {
+		return err
+	}
+	s.agent = agentConn
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            userName,
+		Auth:            auth,
+		HostKeyCallback: hostKey,
+		Timeout:         sshDialTimeout,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot connect to %s: %w", addr, err)
+	}
+	s.client = client
+	s.remote.Host = addr
+	s.remote.User = userName
+	return nil
+}
+
+// splitRemoteHost splits [user@]host[:port] into the login user and a
+// dialable address.
+func splitRemoteHost(spec string) (userName, addr string) {
+	host := spec
+	if u, h, ok := strings.Cut(spec, "@"); ok {
+		userName, host = u, h
+	}
+	if userName == "" {
+		if u, err := user.Current(); err == nil {
+			userName = u.Username
+		}
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "22")
+	}
+	return userName, host
+}
+
+// sshAuth offers the configured identity, else ssh-agent keys and the
+// default unencrypted keys in ~/.ssh. agentConn is the open connection to
+// ssh-agent, if any, for the caller to close once it is done with the keys.
+func sshAuth() (methods []ssh.AuthMethod, agentConn net.Conn, err error) {
+	if Remote.Identity != "" {
+		data, err := os.ReadFile(Remote.Identity)
+		if err != nil {
+			return nil, nil, err
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w (passphrase-protected keys must be loaded into ssh-agent)", Remote.Identity, err)
+		}
+		return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil, nil
+	}
+
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			agentConn = conn
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+	var signers []ssh.Signer
+	home, _ := os.UserHomeDir()
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		data, err := os.ReadFile(filepath.Join(home, ".ssh", name))
+		if err != nil {
+			continue
+		}
+		if signer, err := ssh.ParsePrivateKey(data); err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+	if len(methods) == 0 {
+		return nil, nil, errors.New("no SSH credentials: set remote_identity or load a key into ssh-agent")
+	}
+	return methods, agentConn, nil
+}
+
+// sshSignal maps a local signal onto its SSH protocol name.
+func sshSignal(sig os.Signal) ssh.Signal {
+	switch sig {
+	case os.Interrupt:
+		return ssh.SIGINT
+	case syscall.SIGKILL:
+		return ssh.SIGKILL
+	}
+	return ssh.SIGTERM
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("/._-=:,+@%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,342 @@
+package runner
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/pem"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"coldcase/pkg/session"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshServer is an in-process SSH server that runs exec requests with the
+// local sh, standing in for the remote workstation.
+type sshServer struct {
+	addr    string
+	hostKey ssh.Signer
+	conns   atomic.Int32
+	mu      sync.Mutex
+	execs   []string
+}
+
+func newSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// startSSHServer serves on a loopback port, accepting only clientKey.
+func startSSHServer(t *testing.T, clientKey ssh.PublicKey) *sshServer {
+	t.Helper()
+	srv := &sshServer{hostKey: newSigner(t)}
+	cfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, os.ErrPermission
+			}
+			return nil, nil
+		},
+	}
+	cfg.AddHostKey(srv.hostKey)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	srv.addr = l.Addr().String()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			srv.conns.Add(1)
+			go srv.serve(c, cfg)
+		}
+	}()
+	return srv
+}
+
+func (srv *sshServer) serve(c net.Conn, cfg *ssh.ServerConfig) {
+	conn, chans, reqs, err := ssh.NewServerConn(c, cfg)
+	if err != nil {
+		c.Close()
+		return
+	}
+	defer conn.Close()
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "session only")
+			continue
+		}
+		ch, chReqs, err := nc.Accept()
+		if err != nil {
+			continue
+		}
+		go srv.session(ch, chReqs)
+	}
+}
+
+func (srv *sshServer) session(ch ssh.Channel, reqs <-chan *ssh.Request) {
+	defer ch.Close()
+	for req := range reqs {
+		if req.Type != "exec" {
+			req.Reply(false, nil)
+			continue
+		}
+		var payload struct{ Command string }
+		if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+			req.Reply(false, nil)
+			return
+		}
+		req.Reply(true, nil)
+		srv.mu.Lock()
+		srv.execs = append(srv.execs, payload.Command)
+		srv.mu.Unlock()
+
+		cmd := exec.Command("sh", "-c", payload.Command)
+		cmd.Stdout, cmd.Stderr = ch, ch.Stderr()
+		status := make([]byte, 4)
+		if err := cmd.Run(); err != nil {
+			binary.BigEndian.PutUint32(status, uint32(max(exitCode(err), 1)))
+		}
+		ch.SendRequest("exit-status", false, status)
+		return
+	}
+}
+
+func (srv *sshServer) commands() []string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return append([]string(nil), srv.execs...)
+}
+
+// useRemote points the ssh executor at srv with a client key it accepts and
+// a known_hosts file listing trusted.
+func useRemote(t *testing.T, trusted ssh.PublicKey, paths []PathMap) *sshServer {
+	t.Helper()
+	dir := t.TempDir()
+	_, clientPriv, _ := ed25519.GenerateKey(nil)
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(identity, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	clientSigner, err := ssh.ParsePrivateKey(pem.EncodeToMemory(block))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startSSHServer(t, clientSigner.PublicKey())
+	if trusted == nil {
+		trusted = srv.hostKey.PublicKey()
+	}
+	knownHosts := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(srv.addr)}, trusted) + "\n"
+	if err := os.WriteFile(knownHosts, []byte(line), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	prevRemote, prevBackend := Remote, Backend
+	Remote = RemoteConfig{Host: "analyst@" + srv.addr, Identity: identity, KnownHosts: knownHosts, Paths: paths}
+	Backend = BackendSSH
+	t.Cleanup(func() { Remote, Backend = prevRemote, prevBackend })
+	return srv
+}
+
+func TestSSHRejectsUnknownHostKey(t *testing.T) {
+	dir := inTempDir(t, "mem.raw")
+	srv := useRemote(t, newSigner(t).PublicKey(), []PathMap{{dir, dir}})
+
+	s := &SSH{}
+	defer s.Close()
+	err := s.Prepare(&Job{Opts: RunOpts{Binary: "xxd", Args: []string{"mem.raw"}}})
+	if err == nil || !strings.Contains(err.Error(), "not trusted") {
+		t.Fatalf("Prepare = %v, want an untrusted host key error", err)
+	}
+	if cmds := srv.commands(); len(cmds) != 0 {
+		t.Fatalf("server ran %q for an untrusted host", cmds)
+	}
+}
+
+func TestSSHRefusesUnmappedPathsBeforeConnecting(t *testing.T) {
+	dir := inTempDir(t, "cases/mem.raw", "home/notes.txt")
+	srv := useRemote(t, nil, []PathMap{{dir + "/cases", "/srv/cases"}})
+
+	s := &SSH{}
+	err := s.Prepare(&Job{Opts: RunOpts{Binary: "xxd", Args: []string{"cases/mem.raw", "home/notes.txt"}}})
+	if err == nil || !strings.Contains(err.Error(), dir+"/home/notes.txt") || strings.Contains(err.Error(), "mem.raw") {
+		t.Fatalf("Prepare = %v, want only home/notes.txt reported", err)
+	}
+	if n := srv.conns.Load(); n != 0 {
+		t.Fatalf("%d connections made for an unmapped path", n)
+	}
+}
+
+func TestRemotePathsLongestPrefixWins(t *testing.T) {
+	c := RemoteConfig{Paths: []PathMap{
+		{"/mnt/lab", "/srv/lab"},
+		{"/mnt/lab/cases", "/cases"},
+		{"/mnt/lab/cases/2024", "/archive/2024"},
+	}}
+	for local, want := range map[string]string{
+		"/mnt/lab/tools/x":          "/srv/lab/tools/x",
+		"/mnt/lab/cases/ev.E01":     "/cases/ev.E01",
+		"/mnt/lab/cases/2024/a.raw": "/archive/2024/a.raw",
+		"/mnt/lab/cases":            "/cases",
+		"/mnt/lab/casesold/b":       "/srv/lab/casesold/b",
+	} {
+		if got, ok := c.toRemote(local); !ok || got != want {
+			t.Errorf("toRemote(%s) = %q, %v; want %q", local, got, ok, want)
+		}
+	}
+	if got, ok := c.toRemote("/mnt/labx/y"); ok {
+		t.Errorf("toRemote(/mnt/labx/y) = %q, want unmapped", got)
+	}
+}
+
+func TestShellQuoteRoundTrips(t *testing.T) {
+	words := []string{"", "plain", "a b", "it's", `"dq"`, "$(id)", "`id`", "a;b|c&d", "new\nline", "tab\there", `back\slash`, "*.raw", "~user", "-x", "ünïcode", "'", "''"}
+	line := "printf '%s\\0'"
+	for _, w := range words {
+		line += " " + shellQuote(w)
+	}
+	out, err := exec.Command("sh", "-c", line).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if len(got) != len(words) {
+		t.Fatalf("got %d words, want %d: %q", len(got), len(words), got)
+	}
+	for i := range words {
+		if got[i] != words[i] {
+			t.Errorf("word %d: got %q, want %q", i, got[i], words[i])
+		}
+	}
+}
+
+func TestSSHRunRecordsRemoteExecution(t *testing.T) {
+	prev := session.BaseDir()
+	session.SetBaseDir(t.TempDir())
+	t.Cleanup(func() { session.SetBaseDir(prev) })
+	m := session.NewManager()
+	if _, err := m.Create("remote", "tester", "", session.CreateOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("COLDCASE_SESSION_ID", "remote")
+
+	// The server sees the evidence under store/, the analyst's mount of it
+	// under mnt/; a shorter mapping of the whole directory must lose to the
+	// mnt/ one.
+	dir := inTempDir(t, "store/it's here.txt", "mnt/it's here.txt")
+	srv := useRemote(t, nil, []PathMap{{dir, "/nonexistent"}, {dir + "/mnt", dir + "/store"}})
+	t.Chdir(filepath.Join(dir, "mnt"))
+
+	if err := Run(RunOpts{Binary: "cat", Args: []string{"it's here.txt"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := m.Load("remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Commands) != 1 {
+		t.Fatalf("%d commands logged, want 1", len(s.Commands))
+	}
+	c := s.Commands[0]
+	r := c.Remote
+	if r == nil {
+		t.Fatal("remote execution not recorded")
+	}
+	if r.HostKey != ssh.FingerprintSHA256(srv.hostKey.PublicKey()) || r.HostKeyType != ssh.KeyAlgoED25519 {
+		t.Errorf("host key recorded as %s %s", r.HostKeyType, r.HostKey)
+	}
+	if r.Host != srv.addr || r.User != "analyst" {
+		t.Errorf("recorded %s@%s, want analyst@%s", r.User, r.Host, srv.addr)
+	}
+	wantCmd := "cd " + shellQuote(dir+"/store") + " && exec cat " + shellQuote(dir+"/store/it's here.txt")
+	if r.Command != wantCmd {
+		t.Errorf("command = %q, want %q", r.Command, wantCmd)
+	}
+	if !slices.Contains(srv.commands(), wantCmd) {
+		t.Errorf("server ran %q, want %q among them", srv.commands(), wantCmd)
+	}
+	if c.RunMode != BackendSSH || c.Status != session.StatusCompleted || c.OutputPreview != "x" {
+		t.Errorf("run_mode %s, status %s, preview %q", c.RunMode, c.Status, c.OutputPreview)
+	}
+}
+
+func TestSSHClosesAgentConnection(t *testing.T) {
+	dir := inTempDir(t, "mem.raw")
+	useRemote(t, nil, []PathMap{{dir, dir}})
+
+	// Offer the accepted client key through ssh-agent instead of a file.
+	data, err := os.ReadFile(Remote.Identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.ParseRawPrivateKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+		t.Fatal(err)
+	}
+	Remote.Identity = ""
+	t.Setenv("HOME", t.TempDir())
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	t.Setenv("SSH_AUTH_SOCK", sock)
+	closed := make(chan struct{})
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		agent.ServeAgent(keyring, c)
+		close(closed)
+	}()
+
+	s := &SSH{}
+	if err := s.Prepare(&Job{Opts: RunOpts{Binary: "xxd", Args: []string{"mem.raw"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ssh-agent connection left open after Close")
+	}
+}
@@ -0,0 +1,56 @@
+package runner
+
+import (
+	"io"
+)
+
+// sink wraps a session output writer so that a failing log file never
+// interrupts what the analyst sees: write errors are remembered, not returned.
+type sink struct {
+	w   io.WriteCloser
+	err error
+}
+
+func (s *sink) Write(p []byte) (int, error) {
+	if s.w != nil && s.err == nil {
+		_, s.err = s.w.Write(p)
+	}
+	return len(p), nil
+}
+
+// Close closes the underlying writer and returns the first error seen.
+func (s *sink) Close() error {
+	if s.w == nil {
+		return s.err
+	}
+	if err := s.w.Close(); s.err == nil {
+		s.err = err
+	}
+	return s.err
+}
+
+// prefixBuffer keeps the first limit bytes written to it and discards the rest.
+type prefixBuffer struct {
+	limit int
+	buf   []byte
+	total int64
+}
+
+func (p *prefixBuffer) Write(b []byte) (int, error) {
+	if room := p.limit - len(p.buf); room > 0 {
+		if len(b) < room {
+			room = len(b)
+		}
+		p.buf = append(p.buf, b[:room]...)
+	}
+	p.total += int64(len(b))
+	return len(b), nil
+}
+
+// Preview returns the kept prefix, marked as truncated when more was written.
+func (p *prefixBuffer) Preview() string {
+	if p.total > int64(len(p.buf)) {
+		return string(p.buf) + "..."
+	}
+	return string(p.buf)
+}
@@ -3,55 +3,149 @@ package runner
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
 
 const containerBase = "/data"
 
-// detectMounts scans args for existing host paths, generates "-v host:container"
-// mount strings, and returns remapped args with container-side paths substituted.
-func detectMounts(args []string) (mounts []string, remapped []string) {
-	seen := map[string]string{} // hostDir → containerDir
+// OutputArg describes a flag whose value is a path the tool writes to.
+// Output paths are created on the host and bind-mounted read-write; every
+// other detected path is treated as evidence and mounted read-only.
+type OutputArg struct {
+	// Flag is the option that takes the output path, e.g. "-o" or "-w".
+	// Both "-o path" and "-o=path" forms are recognised.
+	Flag string
+	// Dir marks the value as a directory to create and mount; otherwise it
+	// is a file, created empty and mounted on its own in containers.
+	Dir bool
+}
 
-	for _, arg := range args {
-		// Strip flag prefix so "-f /path/to/file" is handled too.
-		val := strings.TrimLeft(arg, "-")
-		if !looksLikePath(val) {
-			remapped = append(remapped, arg)
-			continue
+// OutputDirFlag is recognised as an output directory for every tool, so
+// tools without metadata can still opt in to a writable mount.
+const OutputDirFlag = "--output-dir"
+
+// outputFor returns the output spec matching flag, if any.
+func outputFor(flag string, outputs []OutputArg) (OutputArg, bool) {
+	if flag == OutputDirFlag {
+		return OutputArg{Flag: OutputDirFlag, Dir: true}, true
+	}
+	for _, o := range outputs {
+		if o.Flag == flag {
+			return o, true
 		}
+	}
+	return OutputArg{}, false
+}
+
+// prepareOutputs creates the host directories that output arguments need:
+// the directory itself for directory outputs, the parent for file outputs.
+// It refuses outputs that would let the tool write to its own inputs: an
+// output directory containing an input, or an output file that is one.
+func prepareOutputs(args []string, spec argSpec) error {
+	inputs := inputPaths(args, spec)
+	for _, r := range outputPaths(args, spec) {
+		abs, err := filepath.Abs(r.Host)
+		if err != nil {
+			return err
+		}
+		for _, in := range inputs {
+			if r.Dir && (in == abs || strings.HasPrefix(in, abs+string(filepath.Separator))) {
+				return fmt.Errorf("output directory %s contains input %s; evidence must stay read-only", abs, in)
+			}
+			if !r.Dir && in == abs {
+				return fmt.Errorf("output %s is also an input; evidence must stay read-only", abs)
+			}
+		}
+		dir := abs
+		if !r.Dir {
+			dir = filepath.Dir(abs)
+		}
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			return fmt.Errorf("creating output directory %s: %w", dir, err)
+		}
+	}
+	return nil
+}
 
-		abs, err := filepath.Abs(val)
-		if err != nil || !pathExists(abs) {
-			remapped = append(remapped, arg)
+// createOutputFiles creates every missing file output, empty, so that it
+// can be bind-mounted on its own without exposing its directory.
+func createOutputFiles(args []string, spec argSpec) error {
+	for _, r := range outputPaths(args, spec) {
+		if r.Dir {
 			continue
 		}
+		f, err := os.OpenFile(r.Host, os.O_CREATE|os.O_WRONLY, 0640)
+		if err != nil {
+			return fmt.Errorf("creating output file %s: %w", r.Host, err)
+		}
+		f.Close()
+	}
+	return nil
+}
 
-		// Mount the parent directory; remap the arg to the container path.
-		dir := filepath.Dir(abs)
-		base := filepath.Base(abs)
+// ContainerMounts returns the mounts and rewritten arguments a container
+// run of opts would use, without running anything. Tool packages test their
+// Outputs and Embedded specs through it.
+func ContainerMounts(opts RunOpts) (mounts, args []string) {
+	return detectMounts(opts.Args, specFor(opts))
+}
 
-		if _, ok := seen[dir]; !ok {
-			idx := len(seen)
-			containerDir := fmt.Sprintf("%s/vol%d", containerBase, idx)
-			seen[dir] = containerDir
-			mounts = append(mounts, fmt.Sprintf("%s:%s:ro", dir, containerDir))
-		}
+// detectMounts finds host paths in args, generates "host:container:mode"
+// mount strings, and returns remapped args with container-side paths
+// substituted. Evidence is mounted read-only through its directory, output
+// directories read-write, and output files read-write on their own (see
+// createOutputFiles), so an input next to an output stays read-only. Each
+// distinct host path gets its own mount, so files with the same basename in
+// different directories never collide.
+func detectMounts(args []string, spec argSpec) (mounts []string, remapped []string) {
+	type mountKey struct {
+		host     string
+		writable bool
+	}
+	seen := map[mountKey]string{} // host path + mode → container path
 
-		containerPath := filepath.Join(seen[dir], base)
-		// Preserve any leading flag prefix.
-		prefix := arg[:len(arg)-len(val)]
-		remapped = append(remapped, prefix+containerPath)
+	// mount binds host at a fresh container directory, or at name inside
+	// it when host is a single file.
+	mount := func(host string, writable bool, name string) string {
+		key := mountKey{host, writable}
+		if c, ok := seen[key]; ok {
+			return c
+		}
+		mode, prefix := "ro", "vol"
+		if writable {
+			mode, prefix = "rw", "out"
+		}
+		target := fmt.Sprintf("%s/%s%d", containerBase, prefix, len(seen))
+		if name != "" {
+			target = path.Join(target, name)
+		}
+		seen[key] = target
+		mounts = append(mounts, fmt.Sprintf("%s:%s:%s", host, target, mode))
+		return target
 	}
+
+	remapped = rewriteArgs(args, spec, func(r pathRef) string {
+		abs, err := filepath.Abs(r.Host)
+		if err != nil {
+			return r.Host
+		}
+		switch {
+		case r.Output && !r.Dir:
+			return mount(abs, true, filepath.Base(abs))
+		case r.Output || isDir(abs):
+			// Directories are mounted as themselves.
+			return mount(abs, r.Output, "")
+		}
+		return path.Join(mount(filepath.Dir(abs), false, ""), filepath.Base(abs))
+	})
 	return mounts, remapped
 }
 
-func looksLikePath(s string) bool {
-	return strings.HasPrefix(s, "/") ||
-		strings.HasPrefix(s, "./") ||
-		strings.HasPrefix(s, "../") ||
-		strings.Contains(s, string(os.PathSeparator))
+func isDir(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && info.IsDir()
 }
 
 func pathExists(p string) bool {
@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// inTempDir creates files (relative paths) in a fresh directory and makes it
+// the working directory for the test.
+func inTempDir(t *testing.T, files ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, f := range files {
+		p := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+	return dir
+}
+
+func TestOutputFileNextToEvidenceStaysSeparate(t *testing.T) {
+	dir := inTempDir(t, "dump.pcap")
+	spec := argSpec{outputs: []OutputArg{{Flag: "-w"}}}
+	args := []string{"-r", "dump.pcap", "-w", "out.pcap"}
+
+	if err := prepareOutputs(args, spec); err != nil {
+		t.Fatal(err)
+	}
+	if err := createOutputFiles(args, spec); err != nil {
+		t.Fatal(err)
+	}
+	mounts, remapped := detectMounts(args, spec)
+
+	want := []string{
+		dir + ":/data/vol0:ro",
+		filepath.Join(dir, "out.pcap") + ":/data/out1/out.pcap:rw",
+	}
+	if !slices.Equal(mounts, want) {
+		t.Fatalf("mounts = %q, want %q", mounts, want)
+	}
+	if got := strings.Join(remapped, " "); got != "-r /data/vol0/dump.pcap -w /data/out1/out.pcap" {
+		t.Fatalf("remapped = %q", got)
+	}
+	for _, m := range mounts {
+		if strings.HasPrefix(m, dir+":") && !strings.HasSuffix(m, ":ro") {
+			t.Fatalf("evidence directory mounted writable: %s", m)
+		}
+	}
+}
+
+func TestPrepareOutputsRefusesOutputsOverInputs(t *testing.T) {
+	inTempDir(t, "case/disk.img", "dump.pcap")
+	for _, tc := range []struct {
+		name string
+		spec argSpec
+		args []string
+	}{
+		{"directory containing input", argSpec{}, []string{"--output-dir", "case", "case/disk.img"}},
+		{"directory is input", argSpec{}, []string{"--output-dir=case", "-i", "case"}},
+		{"file is input", argSpec{outputs: []OutputArg{{Flag: "-w"}}}, []string{"-r", "dump.pcap", "-w", "dump.pcap"}},
+	} {
+		if err := prepareOutputs(tc.args, tc.spec); err == nil {
+			t.Errorf("%s: run not refused", tc.name)
+		}
+	}
+	if err := prepareOutputs([]string{"--output-dir", "out", "case/disk.img"}, argSpec{}); err != nil {
+		t.Errorf("separate output directory refused: %v", err)
+	}
+}
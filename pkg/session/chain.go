@@ -0,0 +1,74 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// ChainError describes the first point at which a session's command log
+// fails verification.
+type ChainError struct {
+	// Position is the 1-based position of the offending entry in the log.
+	Position int
+	// Index is the index recorded in the offending entry.
+	Index  int
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("chain broken at entry #%d (index %d): %s", e.Position, e.Index, e.Reason)
+}
+
+// genesisHash is the PrevHash of the first entry. Binding it to the session ID
+// stops entries from being transplanted between sessions.
+func genesisHash(sessionID string) string {
+	sum := sha256.Sum256([]byte("coldcase-session:" + sessionID))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashEntry returns the SHA-256 digest of every field of e except its own
+// EntryHash and Signature. PrevHash is included, which links the chain.
+func HashEntry(e CommandEntry) (string, error) {
+	e.EntryHash = ""
+	e.Signature = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// chainHead returns the hash the next entry must reference as PrevHash.
+func chainHead(s *Session) string {
+	if n := len(s.Commands); n > 0 {
+		return s.Commands[n-1].EntryHash
+	}
+	return genesisHash(s.ID)
+}
+
+// VerifyChain walks the command log and returns a *ChainError for the first
+// entry that was deleted, reordered or edited after it was logged.
+func VerifyChain(s *Session) error {
+	prev := genesisHash(s.ID)
+	for i, cmd := range s.Commands {
+		pos := i + 1
+		if cmd.Index != pos {
+			return &ChainError{pos, cmd.Index, fmt.Sprintf("expected index %d; entries were deleted or reordered", pos)}
+		}
+		if cmd.PrevHash != prev {
+			return &ChainError{pos, cmd.Index, "previous-entry hash does not match; an earlier entry was removed or altered"}
+		}
+		sum, err := HashEntry(cmd)
+		if err != nil {
+			return &ChainError{pos, cmd.Index, err.Error()}
+		}
+		if sum != cmd.EntryHash {
+			return &ChainError{pos, cmd.Index, "entry contents do not match the recorded hash"}
+		}
+		prev = cmd.EntryHash
+	}
+	return nil
+}
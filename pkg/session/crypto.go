@@ -10,8 +10,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
-	"time"
 
 	"golang.org/x/crypto/pbkdf2"
 )
@@ -40,12 +38,15 @@ func Verify(pub ed25519.PublicKey, data []byte, sigStr string) bool {
 	return ed25519.Verify(pub, data, sig)
 }
 
-// DeriveKey from passphrase using PBKDF2
-func DeriveKey(passphrase string, salt []byte) []byte {
-	if salt == nil {
-		salt = []byte("coldcase-salt") // In production, use unique salts
+// DeriveKey from passphrase using PBKDF2. The salt must be unique per session.
+func DeriveKey(passphrase string, salt []byte) ([]byte, error) {
+	if len(salt) == 0 {
+		return nil, fmt.Errorf("key derivation requires a non-empty salt")
 	}
-	return pbkdf2.Key([]byte(passphrase), salt, 100000, 32, sha256.New)
+	if passphrase == "" {
+		return nil, fmt.Errorf("empty passphrase")
+	}
+	return pbkdf2.Key([]byte(passphrase), salt, 100000, 32, sha256.New), nil
 }
 
 // Encrypt data using AES-256-GCM
@@ -88,41 +89,49 @@ func Decrypt(key, data []byte) ([]byte, error) {
 	return gcm.Open(nil, nonce, ciphertext, nil)
 }
 
-// HashFile computes SHA256 of a file
+// HashFile computes the SHA-256 and MD5 of a file in one pass.
 func HashFile(path string) (sha256Hash, md5Hash string, err error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return "", "", err
-	}
-	defer f.Close()
-
-	h256 := sha256.New()
-	if _, err := io.Copy(h256, f); err != nil {
-		return "", "", err
-	}
-
-	return hex.EncodeToString(h256.Sum(nil)), "", nil
+	d, _, err := HashDigests(path)
+	return d.SHA256, d.MD5, err
 }
 
 // LoadPrivateKey from the default location
 func LoadPrivateKey() (ed25519.PrivateKey, error) {
-	home, _ := os.UserHomeDir()
-	path := filepath.Join(home, ".coldcase", "keys", "private.key")
-	data, err := os.ReadFile(path)
+	data, err := os.ReadFile(PrivateKeyPath())
 	if err != nil {
 		return nil, err
 	}
 	if len(data) != ed25519.PrivateKeySize {
 		return nil, fmt.Errorf("invalid private key size: %d", len(data))
 	}
-	return ed25519.PrivateKey(data), nil
+	_, priv, err := ParseKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", PrivateKeyPath(), err)
+	}
+	return priv, nil
+}
+
+// SigningKey returns the private key that signs s: nil for an unsigned
+// session. A signed session must not gain unsigned or foreign-signed
+// records, so a missing key, or one whose fingerprint is not the session's,
+// is an error.
+func SigningKey(s *Session) (ed25519.PrivateKey, error) {
+	if !s.Signed {
+		return nil, nil
+	}
+	priv, err := LoadPrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("session '%s' is signed and needs the investigator private key: %w", s.ID, err)
+	}
+	if fp := Fingerprint(priv.Public().(ed25519.PublicKey)); s.PublicKey != "" && fp != s.PublicKey {
+		return nil, fmt.Errorf("private key %s is not the key %s that signs session '%s'", fp, s.PublicKey, s.ID)
+	}
+	return priv, nil
 }
 
 // LoadPublicKey from the default location
 func LoadPublicKey() (ed25519.PublicKey, error) {
-	home, _ := os.UserHomeDir()
-	path := filepath.Join(home, ".coldcase", "keys", "public.key")
-	data, err := os.ReadFile(path)
+	data, err := os.ReadFile(PublicKeyPath())
 	if err != nil {
 		return nil, err
 	}
@@ -132,13 +141,31 @@ func LoadPublicKey() (ed25519.PublicKey, error) {
 	return ed25519.PublicKey(data), nil
 }
 
-// VerifySession checks all command signatures
+// VerifySession checks the command hash chain and, when pub is given, the
+// signature on every entry. The returned error names the first failing entry.
+// Sessions with a journal also have every journal event and its signature
+// checked, and the snapshot compared against the replayed journal.
 func (m *Manager) VerifySession(s *Session, pub ed25519.PublicKey) error {
+	if err := VerifyChain(s); err != nil {
+		return err
+	}
+	if pub != nil && s.PublicKey != "" && Fingerprint(pub) != s.PublicKey {
+		return fmt.Errorf("public key %s does not match session key %s", Fingerprint(pub), s.PublicKey)
+	}
+	if s.Journal != nil {
+		if err := m.verifyJournal(s, pub); err != nil {
+			return err
+		}
+	}
+	if err := m.verifyManifests(s); err != nil {
+		return err
+	}
+	if pub == nil {
+		return nil
+	}
 	for i, cmd := range s.Commands {
-		// Re-create the data that was signed
-		data := fmt.Sprintf("%d|%s|%s|%s", cmd.Index, cmd.Timestamp.UTC().Format(time.RFC3339), cmd.FullCommand, cmd.WorkingDirectory)
-		if !Verify(pub, []byte(data), cmd.Signature) {
-			return fmt.Errorf("signature verification failed for command #%d", i+1)
+		if !Verify(pub, []byte(cmd.EntryHash), cmd.Signature) {
+			return &ChainError{i + 1, cmd.Index, "signature verification failed"}
 		}
 	}
 	return nil
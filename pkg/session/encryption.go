@@ -0,0 +1,191 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvPassphrase supplies the passphrase of an encrypted session when no
+// interactive prompt is available (e.g. inside tool runs).
+const EnvPassphrase = "COLDCASE_PASSPHRASE"
+
+// encryptedSuffix marks output files written in encrypted sessions.
+const encryptedSuffix = ".enc"
+
+// encryptedLog is the on-disk form of session.json for sessions that also
+// encrypt their log. Only the ID and key parameters stay readable.
+type encryptedLog struct {
+	ID        string `json:"id"`
+	Encrypted bool   `json:"encrypted"`
+	Salt      string `json:"salt"`
+	KeyCheck  string `json:"key_check"`
+	Payload   []byte `json:"payload"`
+}
+
+// envPassphrase is the default passphrase source for a Manager.
+func envPassphrase(id string) (string, error) {
+	if p := os.Getenv(EnvPassphrase); p != "" {
+		return p, nil
+	}
+	return "", fmt.Errorf("encrypted session '%s' requires a passphrase (set %s)", id, EnvPassphrase)
+}
+
+func newSalt() (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(salt), nil
+}
+
+// keyCheck lets a wrong passphrase be rejected up front instead of surfacing
+// as a GCM authentication failure on the first file.
+func keyCheck(key []byte) string {
+	sum := sha256.Sum256(append([]byte("coldcase-key-check|"), key...))
+	return hex.EncodeToString(sum[:])
+}
+
+// SetPassphraseFunc sets the callback used to obtain the passphrase of an
+// encrypted session the first time its key is needed.
+func (m *Manager) SetPassphraseFunc(f func(sessionID string) (string, error)) {
+	m.passphrase = f
+	m.keys = map[string][]byte{}
+}
+
+// sessionKey derives (and caches) the AES key for a session from its salt.
+func (m *Manager) sessionKey(id, salt, check string) ([]byte, error) {
+	if key, ok := m.keys[id]; ok {
+		return key, nil
+	}
+	rawSalt, err := hex.DecodeString(salt)
+	if err != nil || len(rawSalt) == 0 {
+		return nil, fmt.Errorf("session '%s' has no valid encryption salt", id)
+	}
+	pass, err := m.passphrase(id)
+	if err != nil {
+		return nil, err
+	}
+	key, err := DeriveKey(pass, rawSalt)
+	if err != nil {
+		return nil, err
+	}
+	if check != "" && subtle.ConstantTimeCompare([]byte(keyCheck(key)), []byte(check)) != 1 {
+		return nil, fmt.Errorf("wrong passphrase for session '%s'", id)
+	}
+	m.keys[id] = key
+	return key, nil
+}
+
+// marshal encodes s for session.json, encrypting it when the session asks for it.
+func (m *Manager) marshal(s *Session) ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil || !s.EncryptLog {
+		return data, err
+	}
+	key, err := m.sessionKey(s.ID, s.Salt, s.KeyCheck)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := Encrypt(key, data)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(encryptedLog{
+		ID:        s.ID,
+		Encrypted: true,
+		Salt:      s.Salt,
+		KeyCheck:  s.KeyCheck,
+		Payload:   payload,
+	}, "", "  ")
+}
+
+// unmarshal decodes session.json, decrypting it first if it is an encrypted log.
+func (m *Manager) unmarshal(data []byte) (*Session, error) {
+	var env encryptedLog
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	if env.Payload != nil {
+		key, err := m.sessionKey(env.ID, env.Salt, env.KeyCheck)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = Decrypt(key, env.Payload); err != nil {
+			return nil, fmt.Errorf("decrypting session '%s': %w", env.ID, err)
+		}
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ReadOutput returns the plaintext of a saved output file, given its path
+// relative to the session directory. Large outputs are better read through
+// OpenOutput.
+func (m *Manager) ReadOutput(s *Session, rel string) ([]byte, error) {
+	r, err := m.OpenOutput(s, rel)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// outputPath returns the absolute path of a saved output file.
+func (m *Manager) outputPath(s *Session, rel string) string {
+	return filepath.Join(m.sessionsDir, s.ID, filepath.FromSlash(rel))
+}
+
+// isEncryptedOutput reports whether a saved output file is encrypted.
+func isEncryptedOutput(rel string) bool {
+	return strings.HasSuffix(rel, encryptedSuffix)
+}
+
+// LoadPreviews fills in the output previews that encrypted sessions keep out
+// of the plaintext log, by decrypting each saved output. It only changes the
+// in-memory session and is meant for exports.
+func (m *Manager) LoadPreviews(s *Session) error {
+	if !s.Encrypted {
+		return nil
+	}
+	for i, c := range s.Commands {
+		if c.OutputPreview != "" || c.OutputFile == "" {
+			continue
+		}
+		out, err := m.readPrefix(s, c.OutputFile, PreviewSize+1)
+		if err != nil {
+			return fmt.Errorf("reading output of command #%d: %w", c.Index, err)
+		}
+		s.Commands[i].OutputPreview = Preview(out)
+	}
+	return nil
+}
+
+// readPrefix returns up to n bytes from the start of a saved output file,
+// decrypting no more of it than needed.
+func (m *Manager) readPrefix(s *Session, rel string, n int64) ([]byte, error) {
+	r, err := m.OpenOutput(s, rel)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, n))
+}
+
+// Preview truncates tool output to the inline preview stored in the log.
+func Preview(output []byte) string {
+	if len(output) > PreviewSize {
+		return string(output[:PreviewSize]) + "..."
+	}
+	return string(output)
+}
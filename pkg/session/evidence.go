@@ -0,0 +1,352 @@
+package session
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// EvidenceIDPrefix starts every evidence ID. IDs are allocated in intake
+// order: EV-001, EV-002, ...
+const EvidenceIDPrefix = "EV-"
+
+// EvidenceRef marks an evidence ID in tool arguments, e.g. "@EV-001".
+const EvidenceRef = "@"
+
+// evidenceDir is the case store inside a session directory.
+const evidenceDir = "evidence"
+
+// Digests are the acquisition hashes of a file.
+type Digests struct {
+	MD5    string
+	SHA1   string
+	SHA256 string
+}
+
+// HashDigests computes the MD5, SHA-1 and SHA-256 of path in one pass and
+// returns them with the number of bytes read.
+func HashDigests(path string) (Digests, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Digests{}, 0, err
+	}
+	defer f.Close()
+	return hashReader(f, nil)
+}
+
+// hashReader hashes r, copying it to w as well when w is non-nil.
+func hashReader(r io.Reader, w io.Writer) (Digests, int64, error) {
+	hMD5, hSHA1, hSHA256 := md5.New(), sha1.New(), sha256.New()
+	writers := []io.Writer{hMD5, hSHA1, hSHA256}
+	if w != nil {
+		writers = append(writers, w)
+	}
+	n, err := io.Copy(io.MultiWriter(writers...), r)
+	if err != nil {
+		return Digests{}, n, err
+	}
+	return Digests{
+		MD5:    hex.EncodeToString(hMD5.Sum(nil)),
+		SHA1:   hex.EncodeToString(hSHA1.Sum(nil)),
+		SHA256: hex.EncodeToString(hSHA256.Sum(nil)),
+	}, n, nil
+}
+
+// AcquireOpts describes an evidence intake.
+type AcquireOpts struct {
+	Label      string
+	Source     string
+	Custodian  string
+	AcquiredBy string
+	// Copy stores a copy in the session's case store, written in the same
+	// pass that hashes the original.
+	Copy bool
+}
+
+// AddEvidence hashes the file at path and registers it in s under the next
+// evidence ID, recorded as an evidence_added journal event. s is refreshed
+// with the saved session.
+func (m *Manager) AddEvidence(s *Session, path string, opts AcquireOpts) (*EvidenceFile, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", abs)
+	}
+	if e, ok := s.registered(abs); ok {
+		return nil, fmt.Errorf("%s is already registered as %s", abs, e.ID)
+	}
+
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var tmp *os.File
+	if opts.Copy {
+		store := filepath.Join(m.sessionsDir, s.ID, evidenceDir)
+		if err := os.MkdirAll(store, 0700); err != nil {
+			return nil, err
+		}
+		if tmp, err = os.CreateTemp(store, ".acquire-*"); err != nil {
+			return nil, err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+	}
+
+	captured := time.Now()
+	var d Digests
+	var n int64
+	if tmp != nil {
+		d, n, err = hashReader(f, tmp)
+		if err == nil {
+			err = tmp.Sync()
+		}
+	} else {
+		d, n, err = hashReader(f, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("hashing %s: %w", abs, err)
+	}
+	if n != info.Size() {
+		return nil, fmt.Errorf("%s changed size while it was hashed (%d bytes read, %d expected)", abs, n, info.Size())
+	}
+
+	ev := EvidenceFile{
+		OriginalPath: abs,
+		SHA256:       d.SHA256,
+		SHA1:         d.SHA1,
+		MD5:          d.MD5,
+		CapturedAt:   captured,
+		Size:         n,
+		Modified:     info.ModTime(),
+		Label:        opts.Label,
+		Source:       opts.Source,
+		Custodian:    opts.Custodian,
+		AcquiredBy:   opts.AcquiredBy,
+	}
+	cur, err := m.update(s.ID, func(cur *Session) ([]*Event, error) {
+		if cur.State != StateUnlocked {
+			return nil, fmt.Errorf("session '%s' is %s and read-only", cur.ID, cur.State)
+		}
+		if e, ok := cur.registered(abs); ok {
+			return nil, fmt.Errorf("%s is already registered as %s", abs, e.ID)
+		}
+		ev.ID = nextEvidenceID(cur)
+		if tmp != nil {
+			rel := filepath.Join(evidenceDir, ev.ID, filepath.Base(abs))
+			dst := filepath.Join(m.sessionsDir, cur.ID, rel)
+			if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+				return nil, err
+			}
+			if err := os.Rename(tmp.Name(), dst); err != nil {
+				return nil, err
+			}
+			if err := os.Chmod(dst, 0400); err != nil {
+				return nil, err
+			}
+			// Give the copy the original's mtime so drift checks can trust
+			// an unchanged size and mtime without re-hashing it.
+			if err := os.Chtimes(dst, info.ModTime(), info.ModTime()); err != nil {
+				return nil, err
+			}
+			ev.StoredPath = filepath.ToSlash(rel)
+		}
+		return []*Event{{Type: EventEvidence, Time: captured, Evidence: &ev}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	*s = *cur
+	return &ev, nil
+}
+
+// registered returns the registered evidence originally acquired from path.
+func (s *Session) registered(path string) (EvidenceFile, bool) {
+	for _, e := range s.Evidence {
+		if e.ID != "" && e.OriginalPath == path {
+			return e, true
+		}
+	}
+	return EvidenceFile{}, false
+}
+
+// nextEvidenceID allocates the ID for the next registered evidence file.
+func nextEvidenceID(s *Session) string {
+	n := 0
+	for _, e := range s.Evidence {
+		if e.ID != "" {
+			n++
+		}
+	}
+	return fmt.Sprintf("%s%03d", EvidenceIDPrefix, n+1)
+}
+
+// EvidenceByID returns the registered evidence with the given ID.
+func (s *Session) EvidenceByID(id string) (*EvidenceFile, bool) {
+	for i := range s.Evidence {
+		if s.Evidence[i].ID == id {
+			return &s.Evidence[i], true
+		}
+	}
+	return nil, false
+}
+
+// EvidencePath returns the file tools read for e: its case store copy when
+// there is one, else the original.
+func (m *Manager) EvidencePath(s *Session, e *EvidenceFile) string {
+	if e.StoredPath != "" {
+		return filepath.Join(m.sessionsDir, s.ID, filepath.FromSlash(e.StoredPath))
+	}
+	return e.OriginalPath
+}
+
+// ─── verification ─────────────────────────────────────────────────────────────
+
+// Evidence check results.
+const (
+	EvidenceOK       = "ok"
+	EvidenceModified = "modified"
+	EvidenceMissing  = "missing"
+	EvidenceError    = "error"
+)
+
+// EvidenceCheck is the result of re-hashing one copy of registered evidence
+// against its acquisition hashes.
+type EvidenceCheck struct {
+	ID     string
+	Path   string
+	Stored bool // Path is the case store copy
+	Status string
+	// Mismatch names what differs, e.g. "size, sha256"; Err is set for
+	// EvidenceMissing and EvidenceError.
+	Mismatch string
+	Actual   Digests
+	Size     int64
+	Err      error
+}
+
+// VerifyEvidence re-hashes the registered evidence files in s with the given
+// IDs, or all of them when none are given: the original and its case store
+// copy, compared with the acquisition record. Each file is hashed in full
+// regardless of its size or mtime; evidence not asked for is not read.
+func (m *Manager) VerifyEvidence(s *Session, ids ...string) []EvidenceCheck {
+	var checks []EvidenceCheck
+	for i := range s.Evidence {
+		e := &s.Evidence[i]
+		if e.ID == "" || len(ids) > 0 && !slices.Contains(ids, e.ID) {
+			continue
+		}
+		checks = append(checks, verifyCopy(e, e.OriginalPath, false))
+		if e.StoredPath != "" {
+			checks = append(checks, verifyCopy(e, m.EvidencePath(s, e), true))
+		}
+	}
+	return checks
+}
+
+func verifyCopy(e *EvidenceFile, path string, stored bool) EvidenceCheck {
+	c := EvidenceCheck{ID: e.ID, Path: path, Stored: stored}
+	d, n, err := HashDigests(path)
+	switch {
+	case os.IsNotExist(err):
+		c.Status, c.Err = EvidenceMissing, err
+		return c
+	case err != nil:
+		c.Status, c.Err = EvidenceError, err
+		return c
+	}
+	c.Actual, c.Size = d, n
+	var diff []string
+	if n != e.Size {
+		diff = append(diff, "size")
+	}
+	for _, h := range []struct{ name, want, got string }{
+		{"md5", e.MD5, d.MD5}, {"sha1", e.SHA1, d.SHA1}, {"sha256", e.SHA256, d.SHA256},
+	} {
+		if h.want != "" && h.want != h.got {
+			diff = append(diff, h.name)
+		}
+	}
+	c.Status = EvidenceOK
+	if len(diff) > 0 {
+		c.Status, c.Mismatch = EvidenceModified, strings.Join(diff, ", ")
+	}
+	return c
+}
+
+// Drift describes registered evidence that no longer matches its
+// acquisition record.
+type Drift struct {
+	ID   string
+	Path string
+	// ContentChanged is false when only the modification time moved and the
+	// content still hashes to the acquisition SHA-256.
+	ContentChanged bool
+	Expected       string // acquisition SHA-256
+	Actual         string
+}
+
+func (d Drift) String() string {
+	if !d.ContentChanged {
+		return fmt.Sprintf("%s (%s) modification time changed since acquisition; content unchanged", d.ID, d.Path)
+	}
+	return fmt.Sprintf("%s (%s) changed since acquisition: sha256 %s, expected %s", d.ID, d.Path, d.Actual, d.Expected)
+}
+
+// CheckEvidence compares path, when it is registered evidence or its case
+// store copy, with the acquisition record. A file whose size and mtime are
+// unchanged is trusted without re-hashing; otherwise it is hashed in full.
+// It returns nil when path is not registered evidence or still matches.
+func (m *Manager) CheckEvidence(s *Session, path string) (*Drift, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	var e *EvidenceFile
+	for i := range s.Evidence {
+		f := &s.Evidence[i]
+		if f.ID != "" && (f.OriginalPath == abs || m.EvidencePath(s, f) == abs) {
+			e = f
+			break
+		}
+	}
+	if e == nil {
+		return nil, nil
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil
+	}
+	if info.Size() == e.Size && !e.Modified.IsZero() && info.ModTime().Equal(e.Modified) {
+		return nil, nil
+	}
+	d, _, err := HashDigests(abs)
+	if err != nil {
+		return nil, fmt.Errorf("hashing %s: %w", abs, err)
+	}
+	return &Drift{
+		ID:             e.ID,
+		Path:           abs,
+		ContentChanged: d.SHA256 != e.SHA256,
+		Expected:       e.SHA256,
+		Actual:         d.SHA256,
+	}, nil
+}
@@ -0,0 +1,59 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyEvidenceOnlyReadsRequestedIDs(t *testing.T) {
+	prev := baseDir
+	SetBaseDir(t.TempDir())
+	t.Cleanup(func() { SetBaseDir(prev) })
+	m := NewManager()
+	s, err := m.Create("ev", "tester", "", CreateOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	var evs []*EvidenceFile
+	for _, name := range []string{"disk.img", "mem.raw"} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		ev, err := m.AddEvidence(s, p, AcquireOpts{Copy: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		evs = append(evs, ev)
+	}
+	// EV-001 is gone entirely; asking only for EV-002 must not touch it.
+	if err := os.Remove(evs[0].OriginalPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(m.EvidencePath(s, evs[0])); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := m.VerifyEvidence(s, evs[1].ID)
+	if len(checks) != 2 {
+		t.Fatalf("%d checks for %s, want original and copy", len(checks), evs[1].ID)
+	}
+	for _, c := range checks {
+		if c.ID != evs[1].ID || c.Status != EvidenceOK {
+			t.Errorf("check %+v", c)
+		}
+	}
+
+	missing := 0
+	for _, c := range m.VerifyEvidence(s) {
+		if c.ID == evs[0].ID && c.Status == EvidenceMissing {
+			missing++
+		}
+	}
+	if missing != 2 {
+		t.Errorf("verifying everything reported %d missing copies of %s, want 2", missing, evs[0].ID)
+	}
+}
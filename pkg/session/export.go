@@ -3,6 +3,7 @@ package session
 import (
 	"encoding/json"
 	"fmt"
+	"html"
 	"strings"
 )
 
@@ -24,9 +25,16 @@ func ExportMarkdown(s *Session) (string, error) {
 	sb.WriteString(fmt.Sprintf("- **State**: %s\n", s.State))
 	if s.SealedAt != nil {
 		sb.WriteString(fmt.Sprintf("- **Sealed**: %s\n", s.SealedAt.Format("2006-01-02 15:04:05")))
+		sb.WriteString(fmt.Sprintf("- **Merkle Root**: `%s`\n", s.MerkleRoot))
+		if s.Signature != "" {
+			sb.WriteString(fmt.Sprintf("- **Seal Signature**: `%s`\n", s.Signature))
+		}
 	}
 	sb.WriteString(fmt.Sprintf("- **Signed**: %v\n", s.Signed))
 	sb.WriteString(fmt.Sprintf("- **Encrypted**: %v\n", s.Encrypted))
+	if s.RunMode != "" {
+		sb.WriteString(fmt.Sprintf("- **Run Mode**: %s\n", s.RunMode))
+	}
 	sb.WriteString("\n## Command History\n\n")
 
 	for _, cmd := range s.Commands {
@@ -34,7 +42,28 @@ func ExportMarkdown(s *Session) (string, error) {
 		sb.WriteString(fmt.Sprintf("- **Time**: %s\n", cmd.Timestamp.Format("2006-01-02 15:04:05.000")))
 		sb.WriteString(fmt.Sprintf("- **Full Command**: `%s`\n", cmd.FullCommand))
 		sb.WriteString(fmt.Sprintf("- **Working Dir**: `%s`\n", cmd.WorkingDirectory))
+		sb.WriteString(fmt.Sprintf("- **Tool**: `%s` (%s)\n", cmd.ToolPath, cmd.ToolVersion))
+		if cmd.RunMode != "" {
+			sb.WriteString(fmt.Sprintf("- **Executed**: %s\n", executedAs(cmd)))
+		}
+		if cmd.ImageDigest != "" {
+			sb.WriteString(fmt.Sprintf("- **Image**: `%s@%s`\n", cmd.Image, cmd.ImageDigest))
+		}
+		if cmd.Isolation != nil {
+			sb.WriteString(fmt.Sprintf("- **Isolation**: %s\n", cmd.Isolation))
+		}
+		if r := cmd.Remote; r != nil {
+			sb.WriteString(fmt.Sprintf("- **Remote**: %s@%s (%s %s)\n", r.User, r.Host, r.HostKeyType, r.HostKey))
+			sb.WriteString(fmt.Sprintf("- **Remote Command**: `%s`\n", r.Command))
+		}
+		for _, w := range cmd.DriftWarnings {
+			sb.WriteString(fmt.Sprintf("- **Integrity Warning**: %s\n", w))
+		}
+		sb.WriteString(fmt.Sprintf("- **Exit Code**: %d (%s)\n", cmd.ExitCode, cmd.Status))
 		sb.WriteString(fmt.Sprintf("- **Duration**: %dms\n", cmd.DurationMS))
+		if cmd.EntryHash != "" {
+			sb.WriteString(fmt.Sprintf("- **Entry Hash**: `%s`\n", cmd.EntryHash))
+		}
 		if cmd.Signature != "" {
 			sb.WriteString(fmt.Sprintf("- **Signature**: `%s`\n", cmd.Signature))
 		}
@@ -43,11 +72,39 @@ func ExportMarkdown(s *Session) (string, error) {
 		sb.WriteString("\n```\n\n")
 	}
 
+	if len(s.Notes) > 0 {
+		sb.WriteString("## Notes\n\n")
+		for _, n := range s.Notes {
+			sb.WriteString(fmt.Sprintf("- **%s** (%s): %s\n", n.Time.Format("2006-01-02 15:04:05"), n.Author, n.Text))
+		}
+		sb.WriteString("\n")
+	}
+
 	sb.WriteString("## Evidence Integrity\n\n")
-	sb.WriteString("| Path | SHA256 | Captured |\n")
-	sb.WriteString("|------|--------|----------|\n")
+	sb.WriteString("| ID | Path | SHA256 | Captured |\n")
+	sb.WriteString("|----|------|--------|----------|\n")
 	for _, f := range s.Evidence {
-		sb.WriteString(fmt.Sprintf("| %s | %s | %s |\n", f.OriginalPath, f.SHA256, f.CapturedAt.Format("2006-01-02 15:04:05")))
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", f.ID, f.OriginalPath, f.SHA256, f.CapturedAt.Format("2006-01-02 15:04:05")))
+	}
+	for _, f := range s.Evidence {
+		if f.ID == "" {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("\n### %s\n", strings.TrimSpace(f.ID+" "+f.Label)))
+		sb.WriteString(fmt.Sprintf("- **MD5**: `%s`\n", f.MD5))
+		sb.WriteString(fmt.Sprintf("- **SHA-1**: `%s`\n", f.SHA1))
+		sb.WriteString(fmt.Sprintf("- **SHA-256**: `%s`\n", f.SHA256))
+		sb.WriteString(fmt.Sprintf("- **Size**: %d bytes\n", f.Size))
+		sb.WriteString(fmt.Sprintf("- **Acquired**: %s by %s\n", f.CapturedAt.Format("2006-01-02 15:04:05"), f.AcquiredBy))
+		if f.Source != "" {
+			sb.WriteString(fmt.Sprintf("- **Source**: %s\n", f.Source))
+		}
+		if f.Custodian != "" {
+			sb.WriteString(fmt.Sprintf("- **Custodian**: %s\n", f.Custodian))
+		}
+		if f.StoredPath != "" {
+			sb.WriteString(fmt.Sprintf("- **Case Store Copy**: `%s`\n", f.StoredPath))
+		}
 	}
 
 	return sb.String(), nil
@@ -66,14 +123,37 @@ func ExportHTML(s *Session) (string, error) {
 	sb.WriteString("<div class='meta'><p>Session ID: " + s.ID + "<br>")
 	sb.WriteString("Investigator: " + s.Investigator + " (" + s.Email + ")<br>")
 	sb.WriteString("Created: " + s.Created.Format("2006-01-02 15:04:05") + "<br>")
-	sb.WriteString("State: " + string(s.State) + "</p></div>")
+	sb.WriteString("State: " + string(s.State))
+	if s.SealedAt != nil {
+		sb.WriteString("<br>Sealed: " + s.SealedAt.Format("2006-01-02 15:04:05"))
+		sb.WriteString("<br>Merkle Root: <code>" + s.MerkleRoot + "</code>")
+	}
+	sb.WriteString("</p></div>")
 
 	sb.WriteString("<h2>Command History</h2>")
 	for _, cmd := range s.Commands {
 		sb.WriteString("<div class='cmd'>")
 		sb.WriteString("<h3>[" + fmt.Sprint(cmd.Index) + "] " + cmd.Command + "</h3>")
 		sb.WriteString("<p class='meta'>Timestamp: " + cmd.Timestamp.Format("2006-01-02 15:04:05.000") + "<br>")
-		sb.WriteString("Full Command: <code>" + cmd.FullCommand + "</code></p>")
+		sb.WriteString("Full Command: <code>" + cmd.FullCommand + "</code><br>")
+		sb.WriteString("Tool: <code>" + cmd.ToolPath + "</code> (" + cmd.ToolVersion + ")<br>")
+		if cmd.RunMode != "" {
+			sb.WriteString("Executed: " + executedAs(cmd) + "<br>")
+		}
+		if cmd.ImageDigest != "" {
+			sb.WriteString("Image: <code>" + cmd.Image + "@" + cmd.ImageDigest + "</code><br>")
+		}
+		if cmd.Isolation != nil {
+			sb.WriteString("Isolation: " + cmd.Isolation.String() + "<br>")
+		}
+		if r := cmd.Remote; r != nil {
+			sb.WriteString("Remote: " + r.User + "@" + r.Host + " (" + r.HostKeyType + " " + r.HostKey + ")<br>")
+			sb.WriteString("Remote Command: <code>" + r.Command + "</code><br>")
+		}
+		for _, w := range cmd.DriftWarnings {
+			sb.WriteString("<strong>Integrity Warning:</strong> " + html.EscapeString(w) + "<br>")
+		}
+		sb.WriteString("Exit Code: " + fmt.Sprint(cmd.ExitCode) + " (" + string(cmd.Status) + ")</p>")
 		sb.WriteString("<strong>Output Preview:</strong><pre>" + cmd.OutputPreview + "</pre>")
 		if cmd.Signature != "" {
 			sb.WriteString("<p class='meta'>Signature: <code>" + cmd.Signature + "</code></p>")
@@ -81,12 +161,49 @@ func ExportHTML(s *Session) (string, error) {
 		sb.WriteString("</div>")
 	}
 
+	if len(s.Notes) > 0 {
+		sb.WriteString("<h2>Notes</h2><ul>")
+		for _, n := range s.Notes {
+			sb.WriteString("<li><strong>" + n.Time.Format("2006-01-02 15:04:05") + "</strong> (" + html.EscapeString(n.Author) + "): " + html.EscapeString(n.Text) + "</li>")
+		}
+		sb.WriteString("</ul>")
+	}
+
 	sb.WriteString("<h2>Evidence Integrity</h2>")
-	sb.WriteString("<table><tr><th>Path</th><th>SHA256</th><th>Captured</th></tr>")
+	sb.WriteString("<table><tr><th>ID</th><th>Path</th><th>SHA256</th><th>Captured</th></tr>")
 	for _, f := range s.Evidence {
-		sb.WriteString("<tr><td>" + f.OriginalPath + "</td><td><code>" + f.SHA256 + "</code></td><td>" + f.CapturedAt.Format("2006-01-02 15:04:05") + "</td></tr>")
+		sb.WriteString("<tr><td>" + f.ID + "</td><td>" + f.OriginalPath + "</td><td><code>" + f.SHA256 + "</code></td><td>" + f.CapturedAt.Format("2006-01-02 15:04:05") + "</td></tr>")
 	}
-	sb.WriteString("</table><p>&nbsp;</p></body></html>")
+	sb.WriteString("</table>")
+	for _, f := range s.Evidence {
+		if f.ID == "" {
+			continue
+		}
+		sb.WriteString("<h3>" + f.ID + " " + html.EscapeString(f.Label) + "</h3><p class='meta'>")
+		sb.WriteString("MD5: <code>" + f.MD5 + "</code><br>SHA-1: <code>" + f.SHA1 + "</code><br>SHA-256: <code>" + f.SHA256 + "</code><br>")
+		sb.WriteString("Size: " + fmt.Sprint(f.Size) + " bytes<br>")
+		sb.WriteString("Acquired: " + f.CapturedAt.Format("2006-01-02 15:04:05") + " by " + html.EscapeString(f.AcquiredBy))
+		if f.Source != "" {
+			sb.WriteString("<br>Source: " + html.EscapeString(f.Source))
+		}
+		if f.Custodian != "" {
+			sb.WriteString("<br>Custodian: " + html.EscapeString(f.Custodian))
+		}
+		if f.StoredPath != "" {
+			sb.WriteString("<br>Case Store Copy: <code>" + f.StoredPath + "</code>")
+		}
+		sb.WriteString("</p>")
+	}
+	sb.WriteString("<p>&nbsp;</p></body></html>")
 
 	return sb.String(), nil
 }
+
+// executedAs describes where a command ran and, for local runs, the policy
+// that chose the path.
+func executedAs(cmd CommandEntry) string {
+	if cmd.RunPolicy == "" {
+		return cmd.RunMode
+	}
+	return cmd.RunMode + " (" + cmd.RunPolicy + ")"
+}
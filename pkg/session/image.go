@@ -0,0 +1,33 @@
+package session
+
+// ImageChange reports a command that ran against a different digest than
+// the first command in the session that used the same image.
+type ImageChange struct {
+	Index int
+	Image string
+	From  string // digest seen first
+	To    string // digest this command ran against
+}
+
+// ImageChanges walks the container-executed commands of s and returns every
+// entry whose image digest differs from the first one recorded for that
+// image. Runs are pinned per session, so a change means the pin was lost or
+// the log was assembled from different environments.
+func ImageChanges(s *Session) []ImageChange {
+	first := map[string]string{}
+	var changes []ImageChange
+	for _, c := range s.Commands {
+		if c.Image == "" || c.ImageDigest == "" {
+			continue
+		}
+		want, seen := first[c.Image]
+		if !seen {
+			first[c.Image] = c.ImageDigest
+			continue
+		}
+		if c.ImageDigest != want {
+			changes = append(changes, ImageChange{c.Index, c.Image, want, c.ImageDigest})
+		}
+	}
+	return changes
+}
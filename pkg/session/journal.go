@@ -0,0 +1,478 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// journalName is the append-only event log of a session. It is the record of
+// truth; session.json is a snapshot derived from it.
+const journalName = "journal.jsonl"
+
+// snapshotEvery bounds how many events session.json may lag behind the
+// journal. Load replays the missing tail, so between snapshots a logged
+// command costs one appended line instead of a rewrite of the whole log.
+const snapshotEvery = 64
+
+// EventType names what a journal event records.
+type EventType string
+
+const (
+	EventCreated EventType = "session_created"
+	// EventImported carries the full state of a session logged before the
+	// journal existed; it starts that session's journal.
+	EventImported EventType = "session_imported"
+	EventCommand  EventType = "command"
+	EventState    EventType = "state_change"
+	EventEvidence EventType = "evidence_added"
+	EventNote     EventType = "note"
+)
+
+// Event is one line of the journal. Every event is chained to the previous
+// one by hash and, in signed sessions, signed with the investigator key.
+type Event struct {
+	Seq  int       `json:"seq"`
+	Time time.Time `json:"time"`
+	Type EventType `json:"type"`
+
+	Session *Session      `json:"session,omitempty"` // created, imported
+	Command *CommandEntry `json:"command,omitempty"`
+	// ToolVersions and ImagePins hold what was first learned while running Command.
+	ToolVersions map[string]string   `json:"tool_versions,omitempty"`
+	ImagePins    map[string]ImagePin `json:"image_pins,omitempty"`
+	State        *StateChange        `json:"state,omitempty"`
+	Evidence     *EvidenceFile       `json:"evidence,omitempty"`
+	Note         *Note               `json:"note,omitempty"`
+
+	PrevHash  string `json:"prev_hash"` // Hash of the previous event
+	Hash      string `json:"hash"`      // SHA-256 over every other field
+	Signature string `json:"signature,omitempty"`
+}
+
+// StateChange records a state transition; sealing also records the seal.
+type StateChange struct {
+	From          State        `json:"from"`
+	To            State        `json:"to"`
+	SealedAt      *time.Time   `json:"sealed_at,omitempty"`
+	MerkleRoot    string       `json:"merkle_root,omitempty"`
+	SealedOutputs []SealedFile `json:"sealed_outputs,omitempty"`
+	Signature     string       `json:"signature,omitempty"`
+}
+
+// Note is a free-text remark added to a session by the investigator.
+type Note struct {
+	Time   time.Time `json:"time"`
+	Author string    `json:"author"`
+	Text   string    `json:"text"`
+}
+
+// JournalPos is the point in the journal a session snapshot reflects.
+type JournalPos struct {
+	Seq    int    `json:"seq"`
+	Head   string `json:"head"`   // Hash of event Seq
+	Offset int64  `json:"offset"` // byte offset just past event Seq
+}
+
+// JournalError describes the first journal event that fails verification.
+type JournalError struct {
+	Seq    int
+	Reason string
+}
+
+func (e *JournalError) Error() string {
+	return fmt.Sprintf("journal broken at event #%d: %s", e.Seq, e.Reason)
+}
+
+// errTorn is returned when the journal ends in an incomplete line.
+var errTorn = errors.New("journal ends in an incomplete event; run 'coldcase session repair'")
+
+// sealedRecord is a journal line of a session that encrypts its log. The
+// first line also carries the key parameters, so the journal can be read
+// without the snapshot.
+type sealedRecord struct {
+	Seq      int    `json:"seq"`
+	ID       string `json:"id,omitempty"`
+	Salt     string `json:"salt,omitempty"`
+	KeyCheck string `json:"key_check,omitempty"`
+	Payload  []byte `json:"payload"`
+}
+
+// journalGenesis is the PrevHash of a session's first event.
+func journalGenesis(sessionID string) string {
+	sum := sha256.Sum256([]byte("coldcase-journal:" + sessionID))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashEvent returns the SHA-256 digest of every field of e except its own
+// Hash and Signature.
+func HashEvent(e Event) (string, error) {
+	e.Hash = ""
+	e.Signature = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// apply folds e into s. Replaying a journal from its first event rebuilds
+// the session exactly as it was logged.
+func (s *Session) apply(e *Event) error {
+	switch e.Type {
+	case EventCreated, EventImported:
+		if e.Session == nil {
+			return fmt.Errorf("%s event without session", e.Type)
+		}
+		*s = *e.Session
+		if s.Commands == nil {
+			s.Commands = []CommandEntry{}
+		}
+		if s.Evidence == nil {
+			s.Evidence = []EvidenceFile{}
+		}
+	case EventCommand:
+		if e.Command == nil {
+			return errors.New("command event without command")
+		}
+		s.Commands = append(s.Commands, *e.Command)
+		for k, v := range e.ToolVersions {
+			if s.ToolVersions == nil {
+				s.ToolVersions = map[string]string{}
+			}
+			s.ToolVersions[k] = v
+		}
+		for k, v := range e.ImagePins {
+			if s.ImagePins == nil {
+				s.ImagePins = map[string]ImagePin{}
+			}
+			s.ImagePins[k] = v
+		}
+	case EventEvidence:
+		if e.Evidence == nil {
+			return errors.New("evidence event without evidence")
+		}
+		s.Evidence = append(s.Evidence, *e.Evidence)
+	case EventState:
+		c := e.State
+		if c == nil {
+			return errors.New("state event without state")
+		}
+		s.State = c.To
+		if c.To == StateSealed {
+			s.SealedAt, s.MerkleRoot, s.SealedOutputs, s.Signature = c.SealedAt, c.MerkleRoot, c.SealedOutputs, c.Signature
+		}
+	case EventNote:
+		if e.Note == nil {
+			return errors.New("note event without note")
+		}
+		s.Notes = append(s.Notes, *e.Note)
+	default:
+		return fmt.Errorf("unknown event type %q", e.Type)
+	}
+	return nil
+}
+
+// ─── writing ──────────────────────────────────────────────────────────────────
+
+// appendEvents chains, signs and appends events to the journal of s, applies
+// them to s and refreshes the snapshot when it has fallen too far behind.
+// The caller holds the session lock. A session that has no journal yet gets
+// one, starting with an EventImported of its current state.
+func (m *Manager) appendEvents(s *Session, events []*Event) error {
+	if s.Journal == nil && (len(events) == 0 || events[0].Type != EventCreated) {
+		base := *s
+		events = append([]*Event{{Type: EventImported, Session: &base}}, events...)
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	pos := JournalPos{Head: journalGenesis(s.ID)}
+	if s.Journal != nil {
+		pos = *s.Journal
+	}
+
+	if err := m.checkTail(s); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(m.journalPath(s.ID), os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	priv, err := SigningKey(s)
+	if err != nil {
+		return err
+	}
+	snapshot := pos.Seq-s.snapshotSeq+len(events) >= snapshotEvery
+	var buf bytes.Buffer
+	for _, e := range events {
+		e.Seq, e.PrevHash = pos.Seq+1, pos.Head
+		if e.Time.IsZero() {
+			e.Time = time.Now()
+		}
+		if e.Hash, err = HashEvent(*e); err != nil {
+			return err
+		}
+		if priv != nil {
+			e.Signature = Sign(priv, []byte(e.Hash))
+		}
+		line, err := m.encodeEvent(s, e)
+		if err != nil {
+			return err
+		}
+		if err := s.apply(e); err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		pos = JournalPos{Seq: e.Seq, Head: e.Hash, Offset: pos.Offset + int64(len(line)) + 1}
+		snapshot = snapshot || e.Type != EventCommand && e.Type != EventEvidence
+	}
+	s.Journal = &pos
+
+	if _, err := f.WriteAt(buf.Bytes(), s.Journal.Offset-int64(buf.Len())); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if !snapshot {
+		return nil
+	}
+	return m.Save(s)
+}
+
+// checkTail refuses to append after an incomplete final line: the journal
+// must end exactly where the last event of s does.
+func (m *Manager) checkTail(s *Session) error {
+	var end int64
+	if s.Journal != nil {
+		end = s.Journal.Offset
+	}
+	info, err := os.Stat(m.journalPath(s.ID))
+	switch {
+	case os.IsNotExist(err) && end == 0:
+		return nil
+	case err != nil:
+		return err
+	case info.Size() != end:
+		return errTorn
+	}
+	return nil
+}
+
+// encodeEvent renders e as one journal line, encrypted when s encrypts its log.
+func (m *Manager) encodeEvent(s *Session, e *Event) ([]byte, error) {
+	data, err := json.Marshal(e)
+	if err != nil || !s.EncryptLog {
+		return data, err
+	}
+	key, err := m.sessionKey(s.ID, s.Salt, s.KeyCheck)
+	if err != nil {
+		return nil, err
+	}
+	rec := sealedRecord{Seq: e.Seq}
+	if rec.Payload, err = Encrypt(key, data); err != nil {
+		return nil, err
+	}
+	if e.Seq == 1 {
+		rec.ID, rec.Salt, rec.KeyCheck = s.ID, s.Salt, s.KeyCheck
+	}
+	return json.Marshal(rec)
+}
+
+// ─── reading ──────────────────────────────────────────────────────────────────
+
+// journalEvent is a decoded journal line and the offset just past it.
+type journalEvent struct {
+	*Event
+	end int64
+}
+
+// readJournal decodes the journal of session id from byte offset on. salt
+// and check decrypt an encrypted log when reading starts past its first line.
+// A final line without a newline, which is a write in progress or one cut
+// short by a crash, is not returned; torn is its length.
+func (m *Manager) readJournal(id string, offset int64, salt, check string) (events []journalEvent, torn int64, err error) {
+	f, err := os.Open(m.journalPath(id))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	r := bufio.NewReaderSize(f, 64*1024)
+	pos := offset
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return events, int64(len(line)), nil
+		}
+		if err != nil {
+			return events, 0, err
+		}
+		pos += int64(len(line))
+
+		var rec sealedRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return events, 0, &JournalError{len(events) + 1, "malformed line: " + err.Error()}
+		}
+		data := line
+		if rec.Payload != nil {
+			if rec.Salt != "" {
+				salt, check = rec.Salt, rec.KeyCheck
+			}
+			key, err := m.sessionKey(id, salt, check)
+			if err != nil {
+				return events, 0, err
+			}
+			if data, err = Decrypt(key, rec.Payload); err != nil {
+				return events, 0, &JournalError{rec.Seq, "cannot decrypt: " + err.Error()}
+			}
+		}
+		var e Event
+		if err := json.Unmarshal(data, &e); err != nil {
+			return events, 0, &JournalError{rec.Seq, "malformed event: " + err.Error()}
+		}
+		if rec.Payload != nil && e.Seq != rec.Seq {
+			return events, 0, &JournalError{rec.Seq, fmt.Sprintf("encrypted record claims seq %d", e.Seq)}
+		}
+		events = append(events, journalEvent{&e, pos})
+	}
+}
+
+// checkEvent verifies that e correctly follows the journal position pos.
+func checkEvent(e *Event, pos JournalPos, pub ed25519.PublicKey) error {
+	if e.Seq != pos.Seq+1 {
+		return &JournalError{pos.Seq + 1, fmt.Sprintf("found event #%d; events were deleted or reordered", e.Seq)}
+	}
+	if e.PrevHash != pos.Head {
+		return &JournalError{e.Seq, "previous-event hash does not match; an earlier event was removed or altered"}
+	}
+	sum, err := HashEvent(*e)
+	if err != nil {
+		return &JournalError{e.Seq, err.Error()}
+	}
+	if sum != e.Hash {
+		return &JournalError{e.Seq, "event contents do not match the recorded hash"}
+	}
+	if pub != nil && !Verify(pub, []byte(e.Hash), e.Signature) {
+		return &JournalError{e.Seq, "signature verification failed"}
+	}
+	return nil
+}
+
+// replayTail applies the events logged after the snapshot s was taken.
+func (m *Manager) replayTail(s *Session) error {
+	events, _, err := m.readJournal(s.ID, s.Journal.Offset, s.Salt, s.KeyCheck)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if err := checkEvent(e.Event, *s.Journal, nil); err != nil {
+			return fmt.Errorf("%w (session.json may be stale; run 'coldcase session repair')", err)
+		}
+		if err := s.apply(e.Event); err != nil {
+			return &JournalError{e.Seq, err.Error()}
+		}
+		s.Journal = &JournalPos{Seq: e.Seq, Head: e.Hash, Offset: e.end}
+	}
+	return nil
+}
+
+// rebuild replays the whole journal of session id, verifying the chain and,
+// when pub is given, every signature.
+func (m *Manager) rebuild(id string, pub ed25519.PublicKey) (s *Session, torn int64, err error) {
+	events, torn, err := m.readJournal(id, 0, "", "")
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(events) == 0 {
+		return nil, torn, fmt.Errorf("journal of session '%s' is empty", id)
+	}
+	if t := events[0].Type; t != EventCreated && t != EventImported {
+		return nil, torn, &JournalError{1, fmt.Sprintf("journal starts with %s", t)}
+	}
+	s = &Session{}
+	pos := JournalPos{Head: journalGenesis(id)}
+	for _, e := range events {
+		if err := checkEvent(e.Event, pos, pub); err != nil {
+			return nil, torn, err
+		}
+		if err := s.apply(e.Event); err != nil {
+			return nil, torn, &JournalError{e.Seq, err.Error()}
+		}
+		pos = JournalPos{Seq: e.Seq, Head: e.Hash, Offset: e.end}
+	}
+	if s.ID != id {
+		return nil, torn, &JournalError{1, fmt.Sprintf("journal belongs to session '%s'", s.ID)}
+	}
+	s.Journal = &pos
+	return s, torn, nil
+}
+
+// verifyJournal checks the journal of s end to end and that s, as loaded
+// from the snapshot, matches what the journal rebuilds.
+func (m *Manager) verifyJournal(s *Session, pub ed25519.PublicKey) error {
+	rebuilt, torn, err := m.rebuild(s.ID, pub)
+	if err != nil {
+		return err
+	}
+	if torn > 0 {
+		return errTorn
+	}
+	if chainHead(rebuilt) != chainHead(s) || len(rebuilt.Commands) != len(s.Commands) ||
+		len(rebuilt.Evidence) != len(s.Evidence) || rebuilt.State != s.State || rebuilt.MerkleRoot != s.MerkleRoot {
+		return errors.New("session.json does not match the journal; run 'coldcase session repair'")
+	}
+	return nil
+}
+
+// RepairReport describes what Repair did.
+type RepairReport struct {
+	Events int
+	// TornBytes is the length of the incomplete final line that was removed.
+	TornBytes int64
+}
+
+// Repair rebuilds session.json from the journal of session id. An incomplete
+// final line left by a crash is cut off first; any other damage to the
+// journal is reported and nothing is changed.
+func (m *Manager) Repair(id string) (*RepairReport, error) {
+	unlock, err := m.lock(id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	s, torn, err := m.rebuild(id, nil)
+	if err != nil {
+		return nil, err
+	}
+	if torn > 0 {
+		if err := os.Truncate(m.journalPath(id), s.Journal.Offset); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.Save(s); err != nil {
+		return nil, err
+	}
+	return &RepairReport{Events: s.Journal.Seq, TornBytes: torn}, nil
+}
+
+func (m *Manager) journalPath(id string) string {
+	return filepath.Join(m.sessionsDir, id, journalName)
+}
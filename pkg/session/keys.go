@@ -0,0 +1,83 @@
+package session
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KeysDir returns the directory holding the investigator keypair.
+func KeysDir() string { return filepath.Join(baseDir, "keys") }
+
+// PrivateKeyPath returns the path of the investigator private key.
+func PrivateKeyPath() string { return filepath.Join(KeysDir(), "private.key") }
+
+// PublicKeyPath returns the path of the investigator public key.
+func PublicKeyPath() string { return filepath.Join(KeysDir(), "public.key") }
+
+// KeysExist reports whether an investigator private key is already present.
+func KeysExist() bool {
+	_, err := os.Stat(PrivateKeyPath())
+	return err == nil
+}
+
+// SaveKeys writes the keypair to the keys directory; priv is nil for a
+// verify-only keyring. An existing private or public key is only replaced
+// when force is set. A forced save of a lone public key removes the old
+// private key, which no longer belongs to it.
+func SaveKeys(pub ed25519.PublicKey, priv ed25519.PrivateKey, force bool) error {
+	if !force {
+		for _, p := range []string{PrivateKeyPath(), PublicKeyPath()} {
+			if _, err := os.Stat(p); err == nil {
+				return fmt.Errorf("investigator key already exists at %s (use --force to overwrite)", p)
+			}
+		}
+	}
+	if err := os.MkdirAll(KeysDir(), 0700); err != nil {
+		return err
+	}
+	if priv != nil {
+		if err := os.WriteFile(PrivateKeyPath(), priv, 0600); err != nil {
+			return err
+		}
+	} else if err := os.Remove(PrivateKeyPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.WriteFile(PublicKeyPath(), pub, 0644)
+}
+
+// Fingerprint returns a short, stable identifier for a public key.
+func Fingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return "sha256:" + hex.EncodeToString(sum[:16])
+}
+
+// ParseKey decodes a hex-encoded or raw Ed25519 key. A 32-byte key is
+// returned as a public key; a 64-byte key as a private key with its public
+// half, which must match the half derived from its seed.
+func ParseKey(data []byte) (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	raw := data
+	if txt := strings.TrimSpace(string(data)); len(txt)%2 == 0 {
+		txt = strings.TrimPrefix(txt, "ed25519:")
+		if dec, err := hex.DecodeString(txt); err == nil && (len(dec) == ed25519.PublicKeySize || len(dec) == ed25519.PrivateKeySize) {
+			raw = dec
+		}
+	}
+	switch len(raw) {
+	case ed25519.PublicKeySize:
+		return ed25519.PublicKey(raw), nil, nil
+	case ed25519.PrivateKeySize:
+		priv := ed25519.NewKeyFromSeed(raw[:ed25519.SeedSize])
+		if !bytes.Equal(priv, raw) {
+			return nil, nil, fmt.Errorf("corrupt private key: public half does not match its seed")
+		}
+		return priv.Public().(ed25519.PublicKey), priv, nil
+	default:
+		return nil, nil, fmt.Errorf("unrecognised key of %d bytes", len(raw))
+	}
+}
@@ -0,0 +1,56 @@
+package session
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+)
+
+func TestParseKeyRejectsMismatchedPublicHalf(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotPub, gotPriv, err := ParseKey([]byte(hex.EncodeToString(priv)))
+	if err != nil {
+		t.Fatalf("valid key rejected: %v", err)
+	}
+	if !gotPub.Equal(pub) || !gotPriv.Equal(priv) {
+		t.Fatal("valid key did not round-trip")
+	}
+
+	other, _, _ := ed25519.GenerateKey(nil)
+	spliced := append(append([]byte{}, priv[:ed25519.SeedSize]...), other...)
+	if _, _, err := ParseKey(spliced); err == nil {
+		t.Fatal("spliced key accepted")
+	}
+}
+
+func TestSaveKeysGuardsBothHalves(t *testing.T) {
+	prev := baseDir
+	SetBaseDir(t.TempDir())
+	t.Cleanup(func() { SetBaseDir(prev) })
+
+	pub, priv, _ := GenerateKeys()
+	other, _, _ := GenerateKeys()
+	if err := SaveKeys(pub, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	// A verify-only keyring keeps its trusted public key.
+	if err := SaveKeys(other, priv, false); err == nil {
+		t.Fatal("private key import replaced the trusted public key without force")
+	}
+
+	if err := SaveKeys(pub, priv, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveKeys(other, nil, true); err != nil {
+		t.Fatalf("forced public key import: %v", err)
+	}
+	if KeysExist() {
+		t.Error("stale private key kept next to a different public key")
+	}
+	if got, err := LoadPublicKey(); err != nil || !got.Equal(other) {
+		t.Errorf("public key = %x, %v", got, err)
+	}
+}
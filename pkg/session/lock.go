@@ -0,0 +1,52 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockName is the per-session lock file. Every write to session.json holds
+// an exclusive OS lock on it, so concurrent ColdCase processes in the same
+// session serialise their updates instead of overwriting each other.
+const lockName = ".lock"
+
+// lock takes the exclusive lock on session id, blocking until it is free.
+// The lock is released by the returned function or when the process exits.
+func (m *Manager) lock(id string) (unlock func(), err error) {
+	f, err := os.OpenFile(filepath.Join(m.sessionsDir, id, lockName), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open session lock: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("cannot lock session '%s': %w", id, err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,128 @@
+package session
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// hammerEnv makes the test binary act as one of several ColdCase processes
+// logging into the same session; it holds the base directory.
+const hammerEnv = "COLDCASE_TEST_HAMMER_DIR"
+
+const (
+	hammerProcs    = 4
+	hammerCommands = 10
+)
+
+// TestHammerProcess is the child side of TestConcurrentProcessesKeepChain.
+// Every command starts from the session as loaded when the process began,
+// as a long-running tool would, and writes an output file before logging.
+func TestHammerProcess(t *testing.T) {
+	dir := os.Getenv(hammerEnv)
+	if dir == "" {
+		t.Skip("only run by TestConcurrentProcessesKeepChain")
+	}
+	SetBaseDir(dir)
+	m := NewManager()
+	start, err := m.Load("signed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	worker := os.Getenv("COLDCASE_TEST_WORKER")
+	for i := range hammerCommands {
+		snap := *start
+		l := NewLogger(&snap)
+		w, rel, err := l.OutputWriter("tool"+worker, "stdout.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(w, "worker %s command %d\n", worker, i)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := l.LogCommand(CommandEntry{Command: "tool" + worker, Args: []string{strconv.Itoa(i)}, OutputFile: rel}); err != nil {
+			t.Fatal(err)
+		}
+		if i%3 == 0 {
+			if err := m.AddNote(&snap, "worker "+worker, fmt.Sprintf("after command %d", i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// TestConcurrentProcessesKeepChain runs several processes logging into one
+// signed session at once and checks that no entry is lost, duplicated or
+// chained out of order.
+func TestConcurrentProcessesKeepChain(t *testing.T) {
+	m, s := newSignedSession(t)
+
+	var wg sync.WaitGroup
+	errs := make([]error, hammerProcs)
+	out := make([][]byte, hammerProcs)
+	for w := range hammerProcs {
+		wg.Go(func() {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestHammerProcess$", "-test.count=1")
+			cmd.Env = append(os.Environ(), hammerEnv+"="+baseDir, "COLDCASE_TEST_WORKER="+strconv.Itoa(w))
+			out[w], errs[w] = cmd.CombinedOutput()
+		})
+	}
+	wg.Wait()
+	for w, err := range errs {
+		if err != nil {
+			t.Fatalf("worker %d: %v\n%s", w, err, out[w])
+		}
+	}
+
+	s, err := m.Load(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Commands) != hammerProcs*hammerCommands {
+		t.Fatalf("%d commands logged, want %d", len(s.Commands), hammerProcs*hammerCommands)
+	}
+	perWorker := map[string]int{}
+	for i, c := range s.Commands {
+		if c.Index != i+1 {
+			t.Fatalf("entry %d has index %d", i+1, c.Index)
+		}
+		if i > 0 && c.PrevHash != s.Commands[i-1].EntryHash {
+			t.Fatalf("entry %d does not chain to entry %d", c.Index, i)
+		}
+		// Output files are renamed to carry the index they were logged at.
+		if !strings.Contains(c.OutputFile, fmt.Sprintf("_%03d.", c.Index)) {
+			t.Errorf("entry %d output file %s", c.Index, c.OutputFile)
+		}
+		r, err := m.OpenOutput(s, c.OutputFile)
+		if err != nil {
+			t.Fatalf("entry %d: %v", c.Index, err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		worker := strings.TrimPrefix(c.Command, "tool")
+		if string(data) != fmt.Sprintf("worker %s command %s\n", worker, c.Args[0]) {
+			t.Errorf("entry %d output %q belongs to another command", c.Index, data)
+		}
+		if c.Args[0] != strconv.Itoa(perWorker[worker]) {
+			t.Errorf("worker %s logged command %s out of order", worker, c.Args[0])
+		}
+		perWorker[worker]++
+	}
+	if want := hammerProcs * ((hammerCommands + 2) / 3); len(s.Notes) != want {
+		t.Errorf("%d notes, want %d", len(s.Notes), want)
+	}
+
+	pub, err := LoadPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.VerifySession(s, pub); err != nil {
+		t.Fatalf("session does not verify: %v", err)
+	}
+}
@@ -1,15 +1,35 @@
 package session
 
 import (
+	"crypto/rand"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
 type Logger struct {
 	session *Session
 	manager *Manager
+	// pending holds the output files created for the command being run,
+	// which are renamed once LogCommand allocates its index.
+	pending []pendingOutput
+	// Progress receives progress reports while directory inputs are hashed;
+	// nil keeps hashing quiet.
+	Progress io.Writer
+}
+
+// pendingMarker marks the provisional name of an output file whose command
+// has not been logged yet.
+const pendingMarker = "_pending-"
+
+// pendingOutput is an output file written under a provisional name.
+type pendingOutput struct {
+	rel    string // path relative to the session directory
+	name   string
+	stream string
 }
 
 func NewLogger(s *Session) *Logger {
@@ -19,17 +39,173 @@ func NewLogger(s *Session) *Logger {
 	}
 }
 
-func (l *Logger) LogCommand(entry CommandEntry) error {
-	if l.session.Signed {
-		priv, err := LoadPrivateKey()
-		if err == nil {
-			// Sign a concat of key fields
-			data := fmt.Sprintf("%d|%s|%s|%s", entry.Index, entry.Timestamp.UTC().Format(time.RFC3339), entry.FullCommand, entry.WorkingDirectory)
-			entry.Signature = Sign(priv, []byte(data))
+// LogCommand links entry into the session's hash chain, signs it when the
+// session is signed, and persists the session. The entry's index is
+// allocated here, under the session lock, and returned: the session is
+// reloaded first so entries logged meanwhile by other processes are kept,
+// and the command's output files are renamed to carry the index. The entry
+// is appended to the journal with any evidence hashed for it.
+func (l *Logger) LogCommand(entry CommandEntry) (int, error) {
+	if l.session.Encrypted && !l.session.EncryptLog {
+		// The log itself is plaintext; keep output only in the encrypted file.
+		entry.OutputPreview = ""
+	}
+
+	priv, err := SigningKey(l.session)
+	if err != nil {
+		return 0, err
+	}
+
+	cur, err := l.manager.update(l.session.ID, func(cur *Session) ([]*Event, error) {
+		if cur.State != StateUnlocked {
+			return nil, fmt.Errorf("session '%s' was %s while the command ran", cur.ID, cur.State)
+		}
+		events := l.newEvidence(cur)
+
+		entry.Index = len(cur.Commands) + 1
+		if err := l.renamePending(&entry); err != nil {
+			return nil, err
+		}
+		entry.PrevHash = chainHead(cur)
+		sum, err := HashEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		entry.EntryHash = sum
+		if priv != nil {
+			entry.Signature = Sign(priv, []byte(entry.EntryHash))
+		}
+		cmd := &Event{Type: EventCommand, Command: &entry}
+		cmd.ToolVersions, cmd.ImagePins = l.learned(cur)
+		return append(events, cmd), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	*l.session = *cur
+	return entry.Index, nil
+}
+
+// newEvidence returns an event for every evidence file this process hashed
+// that the freshly loaded session cur does not know yet.
+func (l *Logger) newEvidence(cur *Session) []*Event {
+	var events []*Event
+	for _, ev := range l.session.Evidence {
+		known := false
+		for _, e := range cur.Evidence {
+			if e.OriginalPath == ev.OriginalPath && e.SHA256 == ev.SHA256 && e.Modified.Equal(ev.Modified) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			ev := ev
+			events = append(events, &Event{Type: EventEvidence, Evidence: &ev})
+		}
+	}
+	return events
+}
+
+// learned returns the tool versions and image pins this process recorded
+// while the command ran that cur does not have yet. Values another process
+// recorded first are kept.
+func (l *Logger) learned(cur *Session) (map[string]string, map[string]ImagePin) {
+	var versions map[string]string
+	for k, v := range l.session.ToolVersions {
+		if _, ok := cur.ToolVersions[k]; !ok {
+			if versions == nil {
+				versions = map[string]string{}
+			}
+			versions[k] = v
+		}
+	}
+	var pins map[string]ImagePin
+	for k, v := range l.session.ImagePins {
+		if _, ok := cur.ImagePins[k]; !ok {
+			if pins == nil {
+				pins = map[string]ImagePin{}
+			}
+			pins[k] = v
+		}
+	}
+	return versions, pins
+}
+
+// renamePending gives the command's output files their final indexed names
+// and updates the paths recorded in entry. Files the entry does not refer
+// to, such as a parsed output that failed to save, are removed.
+func (l *Logger) renamePending(entry *CommandEntry) error {
+	sDir := filepath.Join(l.manager.sessionsDir, l.session.ID)
+	for _, p := range l.pending {
+		var refs []*string
+		for _, f := range []*string{&entry.OutputFile, &entry.ErrorFile, &entry.ParsedFile} {
+			if *f == p.rel {
+				refs = append(refs, f)
+			}
 		}
+		if len(refs) == 0 {
+			os.Remove(filepath.Join(sDir, p.rel))
+			continue
+		}
+		final := outputName(p.name, entry.Index, p.stream)
+		if strings.HasSuffix(p.rel, encryptedSuffix) {
+			final += encryptedSuffix
+		}
+		final = filepath.Join("outputs", final)
+		if err := os.Rename(filepath.Join(sDir, p.rel), filepath.Join(sDir, final)); err != nil {
+			return err
+		}
+		for _, f := range refs {
+			*f = final
+		}
+	}
+	l.pending = nil
+	return nil
+}
+
+// Discard removes the output files of a command that could not be logged,
+// so that none are left under provisional names.
+func (l *Logger) Discard() {
+	sDir := filepath.Join(l.manager.sessionsDir, l.session.ID)
+	for _, p := range l.pending {
+		os.Remove(filepath.Join(sDir, p.rel))
+	}
+	l.pending = nil
+}
+
+// outputName is the file name of one output stream of command index.
+func outputName(name string, index int, stream string) string {
+	return fmt.Sprintf("%s_%03d.%s", name, index, stream)
+}
+
+// CachedToolVersion returns the version previously probed for key in this session.
+func (l *Logger) CachedToolVersion(key string) (string, bool) {
+	v, ok := l.session.ToolVersions[key]
+	return v, ok
+}
+
+// CacheToolVersion records a probed tool version; it is persisted with the
+// next logged command.
+func (l *Logger) CacheToolVersion(key, version string) {
+	if l.session.ToolVersions == nil {
+		l.session.ToolVersions = map[string]string{}
+	}
+	l.session.ToolVersions[key] = version
+}
+
+// PinnedImage returns the reference image was pinned to in this session.
+func (l *Logger) PinnedImage(image string) (ImagePin, bool) {
+	pin, ok := l.session.ImagePins[image]
+	return pin, ok
+}
+
+// PinImage records the immutable reference for image; like tool versions it
+// is persisted with the next logged command.
+func (l *Logger) PinImage(image string, pin ImagePin) {
+	if l.session.ImagePins == nil {
+		l.session.ImagePins = map[string]ImagePin{}
 	}
-	l.session.Commands = append(l.session.Commands, entry)
-	return l.manager.Save(l.session)
+	l.session.ImagePins[image] = pin
 }
 
 func (l *Logger) HashInputFile(path string) (FileMetadata, error) {
@@ -42,56 +218,153 @@ func (l *Logger) HashInputFile(path string) (FileMetadata, error) {
 	if err != nil {
 		return FileMetadata{}, err
 	}
+	if info.IsDir() {
+		return l.hashDir(absPath, info)
+	}
 
-	// Simple cache check: see if we already have this file hashed in this session
-	for _, f := range l.session.Evidence {
-		if f.OriginalPath == absPath && f.Size == info.Size() {
-			// In a real implementation, we'd check timestamps too
-			return FileMetadata{
-				Path:     absPath,
-				SHA256:   f.SHA256,
-				Size:     f.Size,
-				Modified: info.ModTime(),
-			}, nil
+	// Reuse the hash recorded in this session for the file, as its original
+	// or as a case store copy, while its size and mtime are unchanged.
+	// Otherwise the file is hashed again.
+	var prev *EvidenceFile
+	var id string
+	for i := range l.session.Evidence {
+		f := &l.session.Evidence[i]
+		if f.OriginalPath != absPath && l.manager.EvidencePath(l.session, f) != absPath {
+			continue
+		}
+		if f.ID != "" {
+			id = f.ID
 		}
+		prev = f
+	}
+	if prev != nil && prev.Size == info.Size() && !prev.Modified.IsZero() && prev.Modified.Equal(info.ModTime()) {
+		return FileMetadata{
+			Path:       absPath,
+			EvidenceID: id,
+			SHA256:     prev.SHA256,
+			MD5:        prev.MD5,
+			Size:       prev.Size,
+			Modified:   info.ModTime(),
+		}, nil
 	}
 
-	sha, _, err := HashFile(absPath)
+	sha, md5sum, err := HashFile(absPath)
 	if err != nil {
 		return FileMetadata{}, err
 	}
 
 	meta := FileMetadata{
-		Path:     absPath,
-		SHA256:   sha,
-		Size:     info.Size(),
-		Modified: info.ModTime(),
+		Path:       absPath,
+		EvidenceID: id,
+		SHA256:     sha,
+		MD5:        md5sum,
+		Size:       info.Size(),
+		Modified:   info.ModTime(),
+	}
+	if prev != nil && prev.SHA256 != sha {
+		meta.PrevSHA256 = prev.SHA256
 	}
 
-	// Add to evidence list if new
+	// Record the new hash and mtime; the file's earlier records stay.
 	l.session.Evidence = append(l.session.Evidence, EvidenceFile{
 		OriginalPath: absPath,
 		SHA256:       sha,
+		MD5:          md5sum,
 		CapturedAt:   time.Now(),
 		Size:         info.Size(),
+		Modified:     info.ModTime(),
 	})
 
 	return meta, nil
 }
 
-func (l *Logger) SaveOutput(index int, name string, output []byte) (string, error) {
-	filename := fmt.Sprintf("%s_%03d.txt", name, index)
-	path := filepath.Join(baseDir, "sessions", l.session.ID, "outputs", filename)
+// hashDir records a directory input as a manifest of every file under it
+// (see HashTree), saved under manifests/ and identified by its root hash.
+// Files that could not be hashed are listed in the manifest and counted in
+// the metadata's Unreadable.
+// Trees are hashed again on every use: a directory's mtime does not change
+// when a file deeper in it is modified.
+func (l *Logger) hashDir(absPath string, info os.FileInfo) (FileMetadata, error) {
+	man, err := HashTree(absPath, l.Progress)
+	if err != nil {
+		return FileMetadata{}, err
+	}
+	root := man.RootHash()
+	rel, err := l.saveManifest(man, root)
+	if err != nil {
+		return FileMetadata{}, fmt.Errorf("cannot save manifest of %s: %w", absPath, err)
+	}
+
+	meta := FileMetadata{
+		Path:       absPath,
+		SHA256:     root,
+		Size:       man.Size,
+		Modified:   info.ModTime(),
+		Files:      len(man.Entries),
+		Manifest:   rel,
+		Unreadable: len(man.Errors),
+	}
+	var prev *EvidenceFile
+	for i := range l.session.Evidence {
+		if l.session.Evidence[i].OriginalPath == absPath {
+			prev = &l.session.Evidence[i]
+		}
+	}
+	if prev != nil && prev.SHA256 == root {
+		return meta, nil
+	}
+	if prev != nil {
+		meta.PrevSHA256 = prev.SHA256
+	}
+	l.session.Evidence = append(l.session.Evidence, EvidenceFile{
+		OriginalPath: absPath,
+		SHA256:       root,
+		CapturedAt:   time.Now(),
+		Size:         man.Size,
+		Modified:     info.ModTime(),
+		Files:        meta.Files,
+		Manifest:     rel,
+	})
+	return meta, nil
+}
+
+// OutputWriter creates the session file that captures one output of the
+// command being run; stream names it and carries the extension
+// ("stdout.txt", "stderr.txt", "parsed.json"). It returns the writer and the
+// path to record in the CommandEntry, relative to the session directory.
+// The file has a provisional name until LogCommand allocates the command's
+// index. In encrypted sessions the output is encrypted as it is written, in
+// 64 KiB records (see encryptingWriter); Close writes the final record.
+func (l *Logger) OutputWriter(name, stream string) (io.WriteCloser, string, error) {
+	nonce := make([]byte, 6)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", err
+	}
+	filename := fmt.Sprintf("%s%s%x.%s", name, pendingMarker, nonce, stream)
 
+	var key []byte
 	if l.session.Encrypted {
-		// Encryption logic would go here if we have the key
-		// For now, write plain as we focus on audit logging
+		var err error
+		key, err = l.manager.sessionKey(l.session.ID, l.session.Salt, l.session.KeyCheck)
+		if err != nil {
+			return nil, "", err
+		}
+		filename += encryptedSuffix
 	}
 
-	err := os.WriteFile(path, output, 0600)
+	path := filepath.Join(l.manager.sessionsDir, l.session.ID, "outputs", filename)
+	rel := filepath.Join("outputs", filename)
+	l.pending = append(l.pending, pendingOutput{rel, name, stream})
+	if key != nil {
+		w, err := newEncryptingWriter(key, path)
+		if err != nil {
+			return nil, "", err
+		}
+		return w, rel, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
-
-	return filepath.Join("outputs", filename), nil
+	return f, rel, nil
 }
@@ -1,7 +1,6 @@
 package session
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -19,23 +18,72 @@ func init() {
 	baseDir = filepath.Join(home, ".coldcase")
 }
 
+// BaseDir returns the ColdCase data directory (default ~/.coldcase).
+func BaseDir() string { return baseDir }
+
+// SetBaseDir moves the ColdCase data directory, e.g. to the configured base_dir.
+func SetBaseDir(dir string) { baseDir = dir }
+
 // Manager handles session persistence and environment integration
 type Manager struct {
 	sessionsDir string
+	// passphrase is asked for the passphrase of an encrypted session on first use.
+	passphrase func(sessionID string) (string, error)
+	keys       map[string][]byte
+}
+
+// CreateOpts controls how a new session is set up.
+type CreateOpts struct {
+	Sign    bool
+	Encrypt bool
+	// EncryptLog also encrypts session.json; implies Encrypt.
+	EncryptLog bool
+	// RunMode fixes the run-mode policy for every command in the session.
+	RunMode string
 }
 
 func NewManager() *Manager {
 	dir := filepath.Join(baseDir, "sessions")
 	_ = os.MkdirAll(dir, 0700)
-	return &Manager{sessionsDir: dir}
+	return &Manager{
+		sessionsDir: dir,
+		passphrase:  envPassphrase,
+		keys:        map[string][]byte{},
+	}
 }
 
-func (m *Manager) Create(id string, investigator, email string, sign, encrypt bool) (*Session, error) {
+func (m *Manager) Create(id string, investigator, email string, opts CreateOpts) (*Session, error) {
 	sDir := filepath.Join(m.sessionsDir, id)
 	if _, err := os.Stat(sDir); err == nil {
 		return nil, fmt.Errorf("session '%s' already exists", id)
 	}
 
+	var fingerprint string
+	if opts.Sign {
+		pub, err := LoadPublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("signed session requested but no investigator key found (run 'coldcase keys generate'): %w", err)
+		}
+		fingerprint = Fingerprint(pub)
+		if _, err := SigningKey(&Session{ID: id, Signed: true, PublicKey: fingerprint}); err != nil {
+			return nil, err
+		}
+	}
+
+	encrypt := opts.Encrypt || opts.EncryptLog
+	var salt, check string
+	if encrypt {
+		var err error
+		if salt, err = newSalt(); err != nil {
+			return nil, err
+		}
+		key, err := m.sessionKey(id, salt, "")
+		if err != nil {
+			return nil, err
+		}
+		check = keyCheck(key)
+	}
+
 	if err := os.MkdirAll(filepath.Join(sDir, "outputs"), 0700); err != nil {
 		return nil, err
 	}
@@ -46,42 +94,145 @@ func (m *Manager) Create(id string, investigator, email string, sign, encrypt bo
 		Email:        email,
 		Created:      time.Now(),
 		State:        StateUnlocked,
-		Signed:       sign,
+		Signed:       opts.Sign,
+		PublicKey:    fingerprint,
 		Encrypted:    encrypt,
+		EncryptLog:   opts.EncryptLog,
+		Salt:         salt,
+		KeyCheck:     check,
+		RunMode:      opts.RunMode,
 		Commands:     []CommandEntry{},
 		Evidence:     []EvidenceFile{},
 	}
 
-	if err := m.Save(s); err != nil {
+	unlock, err := m.lock(id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	header := *s
+	if err := m.appendEvents(s, []*Event{{Type: EventCreated, Time: s.Created, Session: &header}}); err != nil {
 		return nil, err
 	}
 
 	return s, nil
 }
 
+// Load returns the current state of session id: the session.json snapshot
+// plus any journal events logged after it. A missing or unreadable snapshot
+// is rebuilt from the journal.
 func (m *Manager) Load(id string) (*Session, error) {
 	path := filepath.Join(m.sessionsDir, id, "session.json")
 	data, err := os.ReadFile(path)
+	var s *Session
+	if err == nil {
+		s, err = m.unmarshal(data)
+	}
 	if err != nil {
-		return nil, err
+		if _, jerr := os.Stat(m.journalPath(id)); jerr != nil {
+			return nil, err
+		}
+		s, _, err = m.rebuild(id, nil)
+		return s, err
 	}
-
-	var s Session
-	if err := json.Unmarshal(data, &s); err != nil {
+	if s.Journal == nil {
+		return s, nil
+	}
+	s.snapshotSeq = s.Journal.Seq
+	if err := m.replayTail(s); err != nil {
 		return nil, err
 	}
-
-	return &s, nil
+	return s, nil
 }
 
+// Save writes the session.json snapshot of s atomically. The snapshot is
+// derived from the journal, so it can always be rebuilt with Repair.
 func (m *Manager) Save(s *Session) error {
 	path := filepath.Join(m.sessionsDir, s.ID, "session.json")
-	data, err := json.MarshalIndent(s, "", "  ")
+	data, err := m.marshal(s)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return err
+	}
+	if s.Journal != nil {
+		s.snapshotSeq = s.Journal.Seq
+	}
+	return nil
+}
+
+// update reloads session id under its lock and appends the events fn
+// derives from its current state, so changes made meanwhile by other
+// processes are kept. Nothing is written if fn fails.
+func (m *Manager) update(id string, fn func(*Session) ([]*Event, error)) (*Session, error) {
+	unlock, err := m.lock(id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	s, err := m.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkTail(s); err != nil {
+		return nil, err
+	}
+	events, err := fn(s)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.appendEvents(s, events); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetState moves s to the given state and persists it. Transitions that are
+// not allowed by the session state machine are rejected. s is refreshed
+// with the saved session, including commands logged since it was loaded.
+func (m *Manager) SetState(s *Session, to State) error {
+	cur, err := m.update(s.ID, func(cur *Session) ([]*Event, error) {
+		if cur.State == to {
+			return nil, fmt.Errorf("session '%s' is already %s", cur.ID, to)
+		}
+		if !CanTransition(cur.State, to) {
+			return nil, fmt.Errorf("session '%s' cannot move from %s to %s", cur.ID, cur.State, to)
+		}
+		change := &StateChange{From: cur.State, To: to}
+		if to == StateSealed {
+			sealed := *cur
+			if err := m.seal(&sealed); err != nil {
+				return nil, err
+			}
+			change.SealedAt, change.MerkleRoot = sealed.SealedAt, sealed.MerkleRoot
+			change.SealedOutputs, change.Signature = sealed.SealedOutputs, sealed.Signature
+		}
+		return []*Event{{Type: EventState, State: change}}, nil
+	})
 	if err != nil {
 		return err
 	}
+	*s = *cur
+	return nil
+}
 
-	return os.WriteFile(path, data, 0600)
+// AddNote records a free-text note in the session journal. s is refreshed
+// with the saved session.
+func (m *Manager) AddNote(s *Session, author, text string) error {
+	cur, err := m.update(s.ID, func(cur *Session) ([]*Event, error) {
+		if cur.State == StateSealed {
+			return nil, fmt.Errorf("session '%s' is sealed", cur.ID)
+		}
+		note := &Note{Time: time.Now(), Author: author, Text: text}
+		return []*Event{{Type: EventNote, Time: note.Time, Note: note}}, nil
+	})
+	if err != nil {
+		return err
+	}
+	*s = *cur
+	return nil
 }
 
 func (m *Manager) List() ([]string, error) {
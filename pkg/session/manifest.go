@@ -0,0 +1,283 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// manifestDir holds the manifests of directory inputs inside a session
+// directory, each named by its root hash.
+const manifestDir = "manifests"
+
+// manifestHeader starts every manifest. Manifests use hashdeep's file
+// format, so a tree can also be audited with `hashdeep -r -l -a -k`.
+const manifestHeader = "%%%% HASHDEEP-1.0\n%%%% size,md5,sha1,sha256,filename\n"
+
+// progressInterval is how often HashTree reports progress; trees hashed
+// faster than this print nothing.
+const progressInterval = time.Second
+
+// ManifestEntry is one file of a directory manifest.
+type ManifestEntry struct {
+	Path string // relative to the manifest root, slash-separated
+	Size int64
+	Digests
+}
+
+// ManifestError is a file or directory under the manifest root that could
+// not be read or hashed.
+type ManifestError struct {
+	Path string // relative to the manifest root, slash-separated
+	Err  string // without the path, e.g. "open: permission denied"
+}
+
+// Manifest lists every regular file under a directory with its hashes,
+// sorted by path.
+type Manifest struct {
+	Root    string
+	Entries []ManifestEntry
+	Size    int64 // total bytes hashed
+	// Skipped counts symlinks and special files, which are not followed or
+	// hashed.
+	Skipped int
+	// Errors lists what could not be read or hashed, sorted by path. Like
+	// hashdeep, a failing file does not stop the rest of the tree from being
+	// hashed; it is recorded as a comment line instead of a hash.
+	Errors []ManifestError
+}
+
+// Bytes renders m in hashdeep format. Errors follow the entries as "##"
+// comment lines, which hashdeep ignores when auditing.
+func (m *Manifest) Bytes() []byte {
+	var sb strings.Builder
+	sb.WriteString(manifestHeader)
+	for _, e := range m.Entries {
+		fmt.Fprintf(&sb, "%d,%s,%s,%s,%s\n", e.Size, e.MD5, e.SHA1, e.SHA256, e.Path)
+	}
+	for _, e := range m.Errors {
+		fmt.Fprintf(&sb, "## error: %s: %s\n", e.Path, e.Err)
+	}
+	return []byte(sb.String())
+}
+
+// RootHash is the SHA-256 of the rendered manifest. It identifies the tree's
+// contents and names: any added, removed, renamed or modified file changes
+// it, while the tree's location does not.
+func (m *Manifest) RootHash() string {
+	sum := sha256.Sum256(m.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+// HashTree hashes every regular file under root with MD5, SHA-1 and SHA-256,
+// one file per CPU at a time. When progress is non-nil and hashing takes
+// longer than progressInterval, files and bytes done are reported to it.
+// Files and directories that cannot be read, or vanish or change size
+// while the tree is hashed, are recorded in Errors; only an unreadable root
+// fails the whole manifest.
+func HashTree(root string, progress io.Writer) (*Manifest, error) {
+	m := &Manifest{Root: root}
+	fail := func(p string, err error) {
+		rel, _ := filepath.Rel(root, p)
+		m.Errors = append(m.Errors, ManifestError{Path: filepath.ToSlash(rel), Err: manifestErr(err)})
+	}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			fail(p, err)
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			m.Skipped++
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			fail(p, err)
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		m.Entries = append(m.Entries, ManifestEntry{Path: filepath.ToSlash(rel), Size: info.Size()})
+		m.Size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(m.Entries, func(i, j int) bool { return m.Entries[i].Path < m.Entries[j].Path })
+
+	var files, bytes atomic.Int64
+	stop := reportProgress(progress, root, len(m.Entries), m.Size, &files, &bytes)
+	defer stop()
+
+	// Workers write only their own index of hashErrs.
+	hashErrs := make([]error, len(m.Entries))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(runtime.NumCPU(), max(len(m.Entries), 1)) {
+		wg.Go(func() {
+			for i := range jobs {
+				e := &m.Entries[i]
+				d, n, err := HashDigests(filepath.Join(root, filepath.FromSlash(e.Path)))
+				if err == nil && n != e.Size {
+					err = fmt.Errorf("changed size while it was hashed (%d bytes, then %d)", e.Size, n)
+				}
+				if err != nil {
+					hashErrs[i] = err
+					continue
+				}
+				e.Digests = d
+				files.Add(1)
+				bytes.Add(n)
+			}
+		})
+	}
+	for i := range m.Entries {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	hashed := m.Entries[:0]
+	for i, e := range m.Entries {
+		if hashErrs[i] != nil {
+			fail(filepath.Join(root, filepath.FromSlash(e.Path)), hashErrs[i])
+			m.Size -= e.Size
+			continue
+		}
+		hashed = append(hashed, e)
+	}
+	m.Entries = hashed
+	sort.Slice(m.Errors, func(i, j int) bool { return m.Errors[i].Path < m.Errors[j].Path })
+	return m, nil
+}
+
+// manifestErr renders err without the absolute path it may carry, so the
+// manifest, and with it the root hash, does not depend on where the tree is.
+func manifestErr(err error) string {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		return pe.Op + ": " + pe.Err.Error()
+	}
+	return err.Error()
+}
+
+// reportProgress rewrites one status line on w every progressInterval until
+// the returned stop function is called.
+func reportProgress(w io.Writer, root string, files int, size int64, doneFiles, doneBytes *atomic.Int64) (stop func()) {
+	if w == nil {
+		return func() {}
+	}
+	quit := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		printed := false
+		line := func() {
+			fmt.Fprintf(w, "\r[*] Hashing %s: %d/%d files, %s/%s", root,
+				doneFiles.Load(), files, formatBytes(doneBytes.Load()), formatBytes(size))
+			printed = true
+		}
+		for {
+			select {
+			case <-ticker.C:
+				line()
+			case <-quit:
+				if printed {
+					line()
+					fmt.Fprintln(w)
+				}
+				return
+			}
+		}
+	}()
+	return func() {
+		close(quit)
+		<-finished
+	}
+}
+
+// formatBytes renders n in binary units, e.g. "1.5 GiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// saveManifest stores man in the session's manifests/ directory, encrypted
+// in encrypted sessions, and returns its path relative to the session
+// directory. Manifests are named by root hash, so a tree hashed again
+// unchanged reuses the existing file.
+func (l *Logger) saveManifest(man *Manifest, root string) (string, error) {
+	data := man.Bytes()
+	name := root + ".txt"
+	if l.session.Encrypted {
+		key, err := l.manager.sessionKey(l.session.ID, l.session.Salt, l.session.KeyCheck)
+		if err != nil {
+			return "", err
+		}
+		if data, err = Encrypt(key, data); err != nil {
+			return "", err
+		}
+		name += encryptedSuffix
+	}
+	rel := filepath.Join(manifestDir, name)
+	path := filepath.Join(l.manager.sessionsDir, l.session.ID, rel)
+	if _, err := os.Stat(path); err == nil {
+		return filepath.ToSlash(rel), nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// verifyManifests checks that every plaintext directory manifest in s still
+// hashes to the root hash recorded for it. Encrypted manifests are not
+// checked here, as that would need the session key.
+func (m *Manager) verifyManifests(s *Session) error {
+	for _, e := range s.Evidence {
+		if e.Manifest == "" || strings.HasSuffix(e.Manifest, encryptedSuffix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(m.sessionsDir, s.ID, filepath.FromSlash(e.Manifest)))
+		if err != nil {
+			return fmt.Errorf("manifest of %s: %w", e.OriginalPath, err)
+		}
+		if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != e.SHA256 {
+			return fmt.Errorf("manifest %s of %s does not match its root hash %s", e.Manifest, e.OriginalPath, e.SHA256)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHashTreeRecordsUnreadableFiles(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root reads files regardless of their permissions")
+	}
+	root := t.TempDir()
+	for _, f := range []string{"a.txt", "locked/b.txt", "secret.bin", "z.txt"} {
+		p := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []string{"secret.bin", "locked"} {
+		if err := os.Chmod(filepath.Join(root, p), 0); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Chmod(filepath.Join(root, p), 0755) })
+	}
+
+	m, err := HashTree(root, nil)
+	if err != nil {
+		t.Fatalf("one unreadable file aborted the manifest: %v", err)
+	}
+	var hashed []string
+	for _, e := range m.Entries {
+		hashed = append(hashed, e.Path)
+	}
+	if strings.Join(hashed, " ") != "a.txt z.txt" || m.Size != int64(len("a.txt")+len("z.txt")) {
+		t.Errorf("hashed %q (%d bytes), want a.txt and z.txt", hashed, m.Size)
+	}
+	want := "## error: locked: open: permission denied\n## error: secret.bin: open: permission denied\n"
+	if got := string(m.Bytes()); !strings.HasSuffix(got, want) {
+		t.Errorf("manifest:\n%s\nwant it to end with:\n%s", got, want)
+	}
+
+	if _, err := HashTree(filepath.Join(root, "locked"), nil); err == nil {
+		t.Error("unreadable root hashed")
+	}
+}
+
+func TestManifestErrorsChangeRootHash(t *testing.T) {
+	m := &Manifest{Entries: []ManifestEntry{{Path: "a.txt", Size: 1}}}
+	clean := m.RootHash()
+	m.Errors = []ManifestError{{Path: "b.txt", Err: "open: no such file or directory"}}
+	if m.RootHash() == clean {
+		t.Fatal("a file that could not be hashed does not change the root hash")
+	}
+	if !strings.HasSuffix(string(m.Bytes()), "\n## error: b.txt: open: no such file or directory\n") {
+		t.Fatalf("manifest:\n%s", m.Bytes())
+	}
+}
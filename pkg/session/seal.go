@@ -0,0 +1,180 @@
+package session
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SealedFile records the hash of a session output file at seal time.
+type SealedFile struct {
+	Path   string `json:"path"` // relative to the session directory, e.g. "outputs/tshark_001.txt"
+	SHA256 string `json:"sha256"`
+}
+
+// SealReport is the result of re-checking a sealed session against disk.
+type SealReport struct {
+	// RootMatches is false when the recomputed Merkle root differs from the sealed one.
+	RootMatches bool
+	Modified    []string
+	Missing     []string
+	// Added lists output files that appeared after the session was sealed.
+	Added []string
+}
+
+// Intact reports whether nothing changed since the session was sealed.
+func (r *SealReport) Intact() bool {
+	return r.RootMatches && len(r.Modified) == 0 && len(r.Missing) == 0 && len(r.Added) == 0
+}
+
+// merkleRoot computes a binary Merkle tree root over the given leaves.
+// Leaves and interior nodes are domain-separated; an odd node is promoted.
+func merkleRoot(leaves [][]byte) string {
+	if len(leaves) == 0 {
+		sum := sha256.Sum256(nil)
+		return hex.EncodeToString(sum[:])
+	}
+	level := make([][]byte, len(leaves))
+	for i, l := range leaves {
+		sum := sha256.Sum256(append([]byte{0x00}, l...))
+		level[i] = sum[:]
+	}
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			buf := append([]byte{0x01}, level[i]...)
+			buf = append(buf, level[i+1]...)
+			sum := sha256.Sum256(buf)
+			next = append(next, sum[:])
+		}
+		level = next
+	}
+	return hex.EncodeToString(level[0])
+}
+
+// sealLeaves builds the Merkle leaves for a session: every command entry in
+// log order, every output file, then every evidence file.
+func sealLeaves(s *Session, outputs []SealedFile) [][]byte {
+	var leaves [][]byte
+	for _, c := range s.Commands {
+		leaves = append(leaves, []byte("cmd|"+c.EntryHash))
+	}
+	for _, o := range outputs {
+		leaves = append(leaves, []byte("out|"+o.Path+"|"+o.SHA256))
+	}
+	for _, e := range s.Evidence {
+		leaves = append(leaves, []byte(fmt.Sprintf("evi|%s|%s|%d", e.OriginalPath, e.SHA256, e.Size)))
+	}
+	return leaves
+}
+
+// sealPayload is the data covered by the session signature.
+func sealPayload(s *Session) []byte {
+	return []byte(fmt.Sprintf("%s|%s|%s", s.ID, s.SealedAt.UTC().Format(time.RFC3339Nano), s.MerkleRoot))
+}
+
+// hashOutputs hashes every file under the session's outputs/ directory,
+// sorted by path. Provisional files of a command still running in another
+// process are skipped: they are renamed or removed when it is logged.
+func (m *Manager) hashOutputs(s *Session) ([]SealedFile, error) {
+	sDir := filepath.Join(m.sessionsDir, s.ID)
+	var files []SealedFile
+	err := filepath.WalkDir(filepath.Join(sDir, "outputs"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.Contains(d.Name(), pendingMarker) {
+			return nil
+		}
+		sum, _, err := HashFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(sDir, path)
+		files = append(files, SealedFile{Path: filepath.ToSlash(rel), SHA256: sum})
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, err
+}
+
+// seal computes the Merkle root over the session contents and, for signed
+// sessions, signs it with the investigator key.
+func (m *Manager) seal(s *Session) error {
+	if err := VerifyChain(s); err != nil {
+		return fmt.Errorf("refusing to seal a session whose log fails verification: %w", err)
+	}
+	outputs, err := m.hashOutputs(s)
+	if err != nil {
+		return fmt.Errorf("hashing outputs: %w", err)
+	}
+
+	now := time.Now()
+	s.SealedAt = &now
+	s.SealedOutputs = outputs
+	s.MerkleRoot = merkleRoot(sealLeaves(s, outputs))
+
+	if s.Signed {
+		priv, err := SigningKey(s)
+		if err != nil {
+			return err
+		}
+		s.Signature = Sign(priv, sealPayload(s))
+	}
+	return nil
+}
+
+// VerifySeal recomputes the Merkle root of a sealed session and compares the
+// output files on disk against the hashes recorded at seal time. When pub is
+// given, the seal signature is checked too.
+func (m *Manager) VerifySeal(s *Session, pub ed25519.PublicKey) (*SealReport, error) {
+	if s.State != StateSealed || s.SealedAt == nil {
+		return nil, fmt.Errorf("session '%s' is not sealed", s.ID)
+	}
+	if pub != nil && !Verify(pub, sealPayload(s), s.Signature) {
+		return nil, fmt.Errorf("seal signature verification failed")
+	}
+
+	current, err := m.hashOutputs(s)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SealReport{
+		RootMatches: merkleRoot(sealLeaves(s, s.SealedOutputs)) == s.MerkleRoot,
+	}
+
+	onDisk := map[string]string{}
+	for _, f := range current {
+		onDisk[f.Path] = f.SHA256
+	}
+	for _, f := range s.SealedOutputs {
+		sum, ok := onDisk[f.Path]
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, f.Path)
+		case sum != f.SHA256:
+			report.Modified = append(report.Modified, f.Path)
+		}
+		delete(onDisk, f.Path)
+	}
+	for path := range onDisk {
+		report.Added = append(report.Added, path)
+	}
+	sort.Strings(report.Added)
+	return report, nil
+}
@@ -0,0 +1,80 @@
+package session
+
+import (
+	"os"
+	"testing"
+)
+
+// newSignedSession points the package at a fresh base directory with a new
+// investigator keypair and creates a signed session in it.
+func newSignedSession(t *testing.T) (*Manager, *Session) {
+	t.Helper()
+	prev := baseDir
+	SetBaseDir(t.TempDir())
+	t.Cleanup(func() { SetBaseDir(prev) })
+
+	pub, priv, err := GenerateKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveKeys(pub, priv, false); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager()
+	s, err := m.Create("signed", "tester", "", CreateOpts{Sign: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, s
+}
+
+func TestSignedSessionRefusesUnsignedRecords(t *testing.T) {
+	m, s := newSignedSession(t)
+	if _, err := NewLogger(s).LogCommand(CommandEntry{Command: "first"}); err != nil {
+		t.Fatalf("logging with the session key: %v", err)
+	}
+
+	keyData, err := os.ReadFile(PrivateKeyPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(PrivateKeyPath()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewLogger(s).LogCommand(CommandEntry{Command: "unsigned"}); err == nil {
+		t.Fatal("command logged without the private key")
+	}
+	if err := m.AddNote(s, "tester", "unsigned"); err == nil {
+		t.Fatal("note journaled without the private key")
+	}
+
+	// A different investigator's key must not sign into the session.
+	_, foreign, err := GenerateKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(PrivateKeyPath(), foreign, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewLogger(s).LogCommand(CommandEntry{Command: "foreign"}); err == nil {
+		t.Fatal("command signed with a foreign key")
+	}
+
+	if err := os.WriteFile(PrivateKeyPath(), keyData, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cur, err := m.Load(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cur.Commands) != 1 {
+		t.Fatalf("session has %d commands, want 1", len(cur.Commands))
+	}
+	pub, err := LoadPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.VerifySession(cur, pub); err != nil {
+		t.Fatalf("session no longer verifies: %v", err)
+	}
+}
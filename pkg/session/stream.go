@@ -0,0 +1,198 @@
+package session
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// streamMagic starts every encrypted output file.
+const streamMagic = "CCSTRM1\n"
+
+// streamChunk is the plaintext size of one stream record. At most one
+// chunk of output is held in memory, or lost if ColdCase dies mid-run.
+const streamChunk = 64 << 10
+
+// errTruncatedStream reports an encrypted stream that ends without its
+// final record: the writer died, or the file was cut short.
+var errTruncatedStream = errors.New("encrypted output is truncated (no final record)")
+
+// An encrypted stream is streamMagic followed by records, each a 4-byte
+// big-endian length and an AES-256-GCM sealed chunk with its own random
+// nonce. The record's sequence number and a final-record flag are
+// authenticated as additional data, so records cannot be reordered,
+// dropped or cut off unnoticed.
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func streamAAD(seq uint64, final bool) []byte {
+	aad := make([]byte, 9)
+	binary.BigEndian.PutUint64(aad, seq)
+	if final {
+		aad[8] = 1
+	}
+	return aad
+}
+
+// encryptingWriter encrypts a stream to a file in streamChunk records as it
+// is written; Close writes the final record.
+type encryptingWriter struct {
+	f   *os.File
+	gcm cipher.AEAD
+	buf []byte
+	seq uint64
+}
+
+// newEncryptingWriter creates path, which must not exist, for an encrypted stream.
+func newEncryptingWriter(key []byte, path string) (*encryptingWriter, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.WriteString(streamMagic); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &encryptingWriter{f: f, gcm: gcm, buf: make([]byte, 0, streamChunk)}, nil
+}
+
+func (w *encryptingWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		take := min(streamChunk-len(w.buf), len(p))
+		w.buf = append(w.buf, p[:take]...)
+		p = p[take:]
+		if len(w.buf) == streamChunk {
+			if err := w.writeRecord(false); err != nil {
+				return n - len(p) - take, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (w *encryptingWriter) Close() error {
+	err := w.writeRecord(true)
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (w *encryptingWriter) writeRecord(final bool) error {
+	nonce := make([]byte, w.gcm.NonceSize(), w.gcm.NonceSize()+len(w.buf)+w.gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	rec := w.gcm.Seal(nonce, nonce, w.buf, streamAAD(w.seq, final))
+	var hdr [4]byte
+	binary.BigEndian.PutUint32(hdr[:], uint32(len(rec)))
+	if _, err := w.f.Write(append(hdr[:], rec...)); err != nil {
+		return err
+	}
+	w.seq++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// decryptingReader yields the plaintext of an encrypted stream one record
+// at a time.
+type decryptingReader struct {
+	r     *bufio.Reader
+	gcm   cipher.AEAD
+	seq   uint64
+	buf   []byte
+	final bool
+}
+
+func (d *decryptingReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.final {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+func (d *decryptingReader) next() error {
+	var hdr [4]byte
+	if _, err := io.ReadFull(d.r, hdr[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return errTruncatedStream
+		}
+		return err
+	}
+	size := binary.BigEndian.Uint32(hdr[:])
+	if size < uint32(d.gcm.NonceSize()+d.gcm.Overhead()) || size > streamChunk+uint32(d.gcm.NonceSize()+d.gcm.Overhead()) {
+		return fmt.Errorf("encrypted output record %d has invalid length %d", d.seq, size)
+	}
+	rec := make([]byte, size)
+	if _, err := io.ReadFull(d.r, rec); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return errTruncatedStream
+		}
+		return err
+	}
+	nonce, sealed := rec[:d.gcm.NonceSize()], rec[d.gcm.NonceSize():]
+	// The final flag is not stored: try the record as a middle one, then
+	// as the last.
+	for _, final := range []bool{false, true} {
+		if plain, err := d.gcm.Open(nil, nonce, sealed, streamAAD(d.seq, final)); err == nil {
+			d.buf, d.final = plain, final
+			d.seq++
+			return nil
+		}
+	}
+	return fmt.Errorf("encrypted output record %d failed authentication", d.seq)
+}
+
+// OpenOutput opens a saved output file, given its path relative to the
+// session directory, and returns a reader of its plaintext. Encrypted
+// streams are decrypted record by record as they are read.
+func (m *Manager) OpenOutput(s *Session, rel string) (io.ReadCloser, error) {
+	f, err := os.Open(m.outputPath(s, rel))
+	if err != nil || !isEncryptedOutput(rel) {
+		return f, err
+	}
+	key, err := m.sessionKey(s.ID, s.Salt, s.KeyCheck)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r := bufio.NewReader(f)
+	if magic, err := r.Peek(len(streamMagic)); err != nil || string(magic) != streamMagic {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a ColdCase encrypted stream", rel)
+	}
+	r.Discard(len(streamMagic))
+	gcm, err := newGCM(key)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{&decryptingReader{r: r, gcm: gcm}, f}, nil
+}
@@ -0,0 +1,118 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeStream(t *testing.T, key, data []byte, writes int) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "out.enc")
+	w, err := newEncryptingWriter(key, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	step := max(len(data)/writes, 1)
+	for p := data; len(p) > 0; {
+		n := min(step, len(p))
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	enc, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enc
+}
+
+func readStream(key, enc []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(bytes.NewReader(enc[len(streamMagic):]))
+	return io.ReadAll(&decryptingReader{r: r, gcm: gcm})
+}
+
+func TestEncryptedStreamRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	for _, size := range []int{0, 1, streamChunk - 1, streamChunk, 3*streamChunk + 17} {
+		data := make([]byte, size)
+		rand.Read(data)
+		enc := writeStream(t, key, data, 7)
+		got, err := readStream(key, enc)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("size %d: plaintext mismatch", size)
+		}
+	}
+}
+
+func TestEncryptedStreamDetectsTampering(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	data := make([]byte, 2*streamChunk+5)
+	rand.Read(data)
+	enc := writeStream(t, key, data, 1)
+	recLen := 4 + 12 + streamChunk + 16
+	body := enc[len(streamMagic):]
+
+	// Cut after the first record: its plaintext is recovered, then truncation is reported.
+	got, err := readStream(key, enc[:len(streamMagic)+recLen])
+	if err != errTruncatedStream {
+		t.Fatalf("truncated stream: err = %v", err)
+	}
+	if !bytes.Equal(got, data[:streamChunk]) {
+		t.Fatal("truncated stream: first record not recovered")
+	}
+
+	// Swap the first two records.
+	swapped := append([]byte(streamMagic), body[recLen:2*recLen]...)
+	swapped = append(swapped, body[:recLen]...)
+	swapped = append(swapped, body[2*recLen:]...)
+	if _, err := readStream(key, swapped); err == nil {
+		t.Fatal("reordered records accepted")
+	}
+
+	// Drop the final record and present the middle one as the end.
+	if _, err := readStream(key, enc[:len(streamMagic)+2*recLen]); err == nil {
+		t.Fatal("stream without its final record accepted")
+	}
+}
+
+func TestOpenOutputRejectsMissingHeader(t *testing.T) {
+	prev := baseDir
+	SetBaseDir(t.TempDir())
+	t.Cleanup(func() { SetBaseDir(prev) })
+	t.Setenv(EnvPassphrase, "secret")
+
+	m := NewManager()
+	s, err := m.Create("enc", "tester", "", CreateOpts{Encrypt: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel := filepath.Join("outputs", "xxd_001.stdout.txt.enc")
+	if err := os.MkdirAll(filepath.Dir(m.outputPath(s, rel)), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(m.outputPath(s, rel), []byte("CCSTR"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.OpenOutput(s, rel); err == nil || !strings.Contains(err.Error(), "not a ColdCase encrypted stream") {
+		t.Fatalf("OpenOutput = %v", err)
+	}
+}
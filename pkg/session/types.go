@@ -1,9 +1,23 @@
 package session
 
 import (
+	"strings"
 	"time"
 )
 
+// PreviewSize is the number of output bytes kept inline in each CommandEntry.
+const PreviewSize = 500
+
+// CommandStatus records how a logged command ended.
+type CommandStatus string
+
+const (
+	StatusCompleted   CommandStatus = "completed"
+	StatusFailed      CommandStatus = "failed" // non-zero exit or failed to start
+	StatusInterrupted CommandStatus = "interrupted"
+	StatusTimedOut    CommandStatus = "timed_out"
+)
+
 type State string
 
 const (
@@ -13,18 +27,41 @@ const (
 )
 
 type Session struct {
-	ID           string         `json:"id"`
-	Investigator string         `json:"investigator"`
-	Email        string         `json:"email"`
-	Created      time.Time      `json:"created"`
-	State        State          `json:"state"`
-	Encrypted    bool           `json:"encrypted"`
-	Signed       bool           `json:"signed"`
-	PublicKey    string         `json:"public_key,omitempty"`
-	Commands     []CommandEntry `json:"commands"`
-	Evidence     []EvidenceFile `json:"evidence_files"`
-	Signature    string         `json:"signature,omitempty"` // Final session signature
-	SealedAt     *time.Time     `json:"sealed_at,omitempty"`
+	ID           string    `json:"id"`
+	Investigator string    `json:"investigator"`
+	Email        string    `json:"email"`
+	Created      time.Time `json:"created"`
+	State        State     `json:"state"`
+	Encrypted    bool      `json:"encrypted"`
+	// EncryptLog also encrypts session.json itself, not only the outputs.
+	EncryptLog bool `json:"encrypt_log,omitempty"`
+	// Salt and KeyCheck hold the per-session key derivation parameters.
+	Salt      string         `json:"salt,omitempty"`
+	KeyCheck  string         `json:"key_check,omitempty"`
+	Signed    bool           `json:"signed"`
+	PublicKey string         `json:"public_key,omitempty"`
+	Commands  []CommandEntry `json:"commands"`
+	Evidence  []EvidenceFile `json:"evidence_files"`
+	Signature string         `json:"signature,omitempty"` // Final session signature
+	SealedAt  *time.Time     `json:"sealed_at,omitempty"`
+	// MerkleRoot covers every command entry, output file and evidence file at seal time.
+	MerkleRoot    string       `json:"merkle_root,omitempty"`
+	SealedOutputs []SealedFile `json:"sealed_outputs,omitempty"`
+	// ToolVersions caches version probe results, keyed by tool path and probe arguments.
+	ToolVersions map[string]string `json:"tool_versions,omitempty"`
+	// ImagePins maps each container image tag used in the session to the
+	// immutable reference it was resolved to on first use.
+	ImagePins map[string]ImagePin `json:"image_pins,omitempty"`
+	// RunMode is the run-mode policy every command in the session must follow
+	// (e.g. "container-only"); empty leaves it to the tool and global settings.
+	RunMode string `json:"run_mode,omitempty"`
+	Notes   []Note `json:"notes,omitempty"`
+	// Journal is the journal position this snapshot reflects; nil for
+	// sessions that have not been written since the journal was introduced.
+	Journal *JournalPos `json:"journal,omitempty"`
+
+	// snapshotSeq is the journal position of the session.json it was loaded from.
+	snapshotSeq int
 }
 
 type CommandEntry struct {
@@ -37,24 +74,139 @@ type CommandEntry struct {
 	ToolPath         string         `json:"tool_path"`
 	ToolVersion      string         `json:"tool_version"`
 	ExitCode         int            `json:"exit_code"`
+	Status           CommandStatus  `json:"status"`
 	DurationMS       int64          `json:"duration_ms"`
 	OutputPreview    string         `json:"output_preview"`
-	OutputFile       string         `json:"output_file"`
+	OutputFile       string         `json:"output_file"`              // captured stdout
+	ErrorFile        string         `json:"error_file,omitempty"`     // captured stderr
+	ParsedFile       string         `json:"parsed_file,omitempty"`    // structured stdout from the tool's output parser
+	RunMode          string         `json:"run_mode,omitempty"`       // executor that ran it: "native", "container", ...
+	RunPolicy        string         `json:"run_policy,omitempty"`     // policy that chose it, e.g. "prefer-native"
+	Image            string         `json:"image,omitempty"`          // container image tag requested, e.g. "coldcase:latest"
+	ImageDigest      string         `json:"image_digest,omitempty"`   // sha256 digest of the image that actually ran
+	Isolation        *Isolation     `json:"isolation,omitempty"`      // confinement the command ran under
+	EvidenceIDs      []string       `json:"evidence_ids,omitempty"`   // evidence referenced by ID in the arguments
+	Remote           *RemoteExec    `json:"remote,omitempty"`         // remote host the command ran on
+	DriftWarnings    []string       `json:"drift_warnings,omitempty"` // evidence integrity warnings the command ran despite
 	WorkingDirectory string         `json:"working_directory"`
+	PrevHash         string         `json:"prev_hash"`  // EntryHash of the previous entry
+	EntryHash        string         `json:"entry_hash"` // SHA-256 over every other field
 	Signature        string         `json:"signature,omitempty"`
 }
 
+// Isolation records the confinement a command ran under.
+type Isolation struct {
+	// Sandbox names the native sandbox backend ("bwrap" or "userns"); empty
+	// for container runs.
+	Sandbox string `json:"sandbox,omitempty"`
+	// Network is the container network: "none", "bridge" or "host".
+	Network         string   `json:"network"`
+	ReadOnlyRoot    bool     `json:"read_only_root,omitempty"`
+	CapDrop         []string `json:"cap_drop,omitempty"`
+	CapAdd          []string `json:"cap_add,omitempty"`
+	NoNewPrivileges bool     `json:"no_new_privileges,omitempty"`
+	Tmpfs           []string `json:"tmpfs,omitempty"`
+}
+
+// String summarises the isolation, e.g. "network=none read-only cap-drop=ALL".
+func (i Isolation) String() string {
+	parts := []string{"network=" + i.Network}
+	if i.Sandbox != "" {
+		parts = append([]string{"sandbox=" + i.Sandbox}, parts...)
+	}
+	if i.ReadOnlyRoot {
+		parts = append(parts, "read-only")
+	}
+	if len(i.CapDrop) > 0 {
+		parts = append(parts, "cap-drop="+strings.Join(i.CapDrop, ","))
+	}
+	if len(i.CapAdd) > 0 {
+		parts = append(parts, "cap-add="+strings.Join(i.CapAdd, ","))
+	}
+	if i.NoNewPrivileges {
+		parts = append(parts, "no-new-privileges")
+	}
+	if len(i.Tmpfs) > 0 {
+		parts = append(parts, "tmpfs="+strings.Join(i.Tmpfs, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// RemoteExec records where and how a command ran on a remote executor.
+type RemoteExec struct {
+	Host string `json:"host"` // host:port dialled
+	User string `json:"user"`
+	// HostKey is the SHA256 fingerprint of the server's host key, which was
+	// verified against known_hosts before anything ran.
+	HostKey     string `json:"host_key"`
+	HostKeyType string `json:"host_key_type"`
+	// Command is the exact command line run on the server, with paths
+	// translated to the server's evidence store.
+	Command string `json:"command"`
+}
+
+// ImagePin records the immutable reference a container image tag resolved to.
+type ImagePin struct {
+	// Ref is what the runtime is given: repo@sha256:... for images pulled
+	// from a registry, or the bare image ID for locally built ones.
+	Ref      string    `json:"ref"`
+	Digest   string    `json:"digest"`
+	PinnedAt time.Time `json:"pinned_at"`
+}
+
+// FileMetadata describes one input of a command. For a directory, SHA256 is
+// the root hash of its manifest and Size the total of the files under it.
 type FileMetadata struct {
-	Path     string    `json:"path"`
-	SHA256   string    `json:"sha256"`
-	MD5      string    `json:"md5"`
-	Size     int64     `json:"size"`
-	Modified time.Time `json:"modified"`
+	Path       string    `json:"path"`
+	EvidenceID string    `json:"evidence_id,omitempty"` // set when the file is registered evidence
+	SHA256     string    `json:"sha256"`
+	MD5        string    `json:"md5"`
+	Size       int64     `json:"size"`
+	Modified   time.Time `json:"modified"`
+	PrevSHA256 string    `json:"previous_sha256,omitempty"` // earlier hash in this session, when the file changed since
+	Files      int       `json:"files,omitempty"`           // directories: number of files in the manifest
+	Manifest   string    `json:"manifest,omitempty"`        // directories: manifest path, relative to the session directory
+	Unreadable int       `json:"unreadable,omitempty"`      // directories: files that could not be hashed, listed in the manifest
 }
 
+// EvidenceFile is a file the session has hashed. Files registered with
+// `evidence add` carry an ID and acquisition metadata; files that were only
+// seen as tool inputs do not.
 type EvidenceFile struct {
+	ID           string    `json:"id,omitempty"` // e.g. "EV-001"
 	OriginalPath string    `json:"original_path"`
 	SHA256       string    `json:"sha256"`
+	SHA1         string    `json:"sha1,omitempty"`
+	MD5          string    `json:"md5,omitempty"`
 	CapturedAt   time.Time `json:"captured_at"`
 	Size         int64     `json:"file_size"`
+	Modified     time.Time `json:"modified,omitzero"` // mtime when hashed
+	Label        string    `json:"label,omitempty"`
+	Source       string    `json:"source,omitempty"`    // where it was acquired, e.g. "laptop HDD, bay 2"
+	Custodian    string    `json:"custodian,omitempty"` // who handed it over
+	AcquiredBy   string    `json:"acquired_by,omitempty"`
+	// StoredPath is the copy in the case store, relative to the session
+	// directory; tools referring to the evidence by ID read this copy.
+	StoredPath string `json:"stored_path,omitempty"`
+	// Files and Manifest are set for directory inputs, whose SHA256 is the
+	// manifest's root hash.
+	Files    int    `json:"files,omitempty"`
+	Manifest string `json:"manifest,omitempty"`
+}
+
+// transitions lists the states each state may move to. Sealed is terminal:
+// once a session is sealed it can never be unlocked or locked again.
+var transitions = map[State][]State{
+	StateUnlocked: {StateLocked, StateSealed},
+	StateLocked:   {StateUnlocked, StateSealed},
+}
+
+// CanTransition reports whether a session in state from may move to state to.
+func CanTransition(from, to State) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
 }
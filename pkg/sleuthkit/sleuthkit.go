@@ -2,6 +2,7 @@
 package sleuthkit
 
 import (
+	"coldcase/pkg/registry"
 	"coldcase/pkg/runner"
 	"fmt"
 )
@@ -49,3 +50,15 @@ func Tools() []*SleuthKitTool {
 	}
 	return result
 }
+
+// Register adds the Sleuth Kit tools to r.
+func Register(r *registry.Registry) {
+	for _, t := range Tools() {
+		r.Add(registry.Entry{
+			Name:        t.tool,
+			Category:    "Sleuth Kit",
+			Description: t.Description(),
+			Tool:        t,
+		})
+	}
+}
@@ -2,6 +2,7 @@
 package steg
 
 import (
+	"coldcase/pkg/registry"
 	"coldcase/pkg/runner"
 )
 
@@ -32,3 +33,16 @@ func Tools() []*StegTool {
 		{"stegdetect", "", "Automated tool for detecting steganographic content in JPEGs"},
 	}
 }
+
+// Register adds the steganography and media tools to r.
+func Register(r *registry.Registry) {
+	for _, t := range Tools() {
+		r.Add(registry.Entry{
+			Name:        t.name,
+			Binary:      t.bin,
+			Category:    "Steganography & Media",
+			Description: t.desc,
+			Tool:        t,
+		})
+	}
+}
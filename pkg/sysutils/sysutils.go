@@ -3,6 +3,7 @@
 package sysutils
 
 import (
+	"coldcase/pkg/registry"
 	"coldcase/pkg/runner"
 )
 
@@ -29,3 +30,15 @@ func Tools() []*SysUtil {
 		{"ldd", "List dynamic dependencies of executable files"},
 	}
 }
+
+// Register adds the system utility wrappers to r.
+func Register(r *registry.Registry) {
+	for _, t := range Tools() {
+		r.Add(registry.Entry{
+			Name:        t.name,
+			Category:    "System Utilities",
+			Description: t.desc,
+			Tool:        t,
+		})
+	}
+}
@@ -2,6 +2,7 @@
 package timeline
 
 import (
+	"coldcase/pkg/registry"
 	"coldcase/pkg/runner"
 )
 
@@ -19,7 +20,16 @@ func (t *TimelineTool) Run(args []string) error {
 	if bin == "" {
 		bin = t.name
 	}
-	return runner.Run(runner.RunOpts{Binary: bin, Args: args})
+	return runner.Run(runner.RunOpts{Binary: bin, Args: args, Outputs: outputArgs[t.name]})
+}
+
+// outputArgs lists the options through which each tool writes its results.
+var outputArgs = map[string][]runner.OutputArg{
+	"log2timeline": {{Flag: "--storage-file"}, {Flag: "--storage_file"}},
+	"psort":        {{Flag: "-w"}, {Flag: "--write"}},
+	"psteal":       {{Flag: "-w"}, {Flag: "--write"}},
+	"hayabusa":     {{Flag: "-o"}, {Flag: "--output"}},
+	"chainsaw":     {{Flag: "-o"}, {Flag: "--output"}},
 }
 
 // Tools returns all timeline and log analysis tools.
@@ -34,3 +44,61 @@ func Tools() []*TimelineTool {
 		{"chainsaw", "", "Rapid Windows event log analysis with Sigma and built-in rules"},
 	}
 }
+
+// plasoCommands maps the Plaso binaries to their subcommand under "plaso".
+var plasoCommands = map[string]string{
+	"log2timeline": "parse",
+	"psort":        "sort",
+	"psteal":       "psteal",
+}
+
+// parsersTool lists the parsers and presets log2timeline supports.
+type parsersTool struct{ l2t *TimelineTool }
+
+func (p parsersTool) Name() string        { return "parsers" }
+func (p parsersTool) Description() string { return "List all available plaso parsers" }
+func (p parsersTool) Run(args []string) error {
+	return p.l2t.Run(append([]string{"--parsers", "list"}, args...))
+}
+
+// Register adds the timeline tools to r. The Plaso binaries are grouped
+// under the "plaso" command; the rest are top-level commands.
+func Register(r *registry.Registry) {
+	var plaso []*TimelineTool
+	for _, t := range Tools() {
+		if _, ok := plasoCommands[t.name]; ok {
+			plaso = append(plaso, t)
+			continue
+		}
+		r.Add(registry.Entry{
+			Name:        t.name,
+			Binary:      t.bin,
+			Category:    "Timeline & Log Analysis",
+			Description: t.desc,
+			Tool:        t,
+		})
+	}
+
+	var parsers parsersTool
+	for _, t := range plaso {
+		r.Add(registry.Entry{
+			Name:        plasoCommands[t.name],
+			Group:       "plaso",
+			Binary:      t.bin,
+			Category:    "Plaso (log2timeline)",
+			Description: t.desc,
+			Tool:        t,
+		})
+		if t.name == "log2timeline" {
+			parsers.l2t = t
+		}
+	}
+	r.Add(registry.Entry{
+		Name:        parsers.Name(),
+		Group:       "plaso",
+		Binary:      parsers.l2t.bin,
+		Category:    "Plaso (log2timeline)",
+		Description: parsers.Description(),
+		Tool:        parsers,
+	})
+}
@@ -0,0 +1,45 @@
+package timeline
+
+import (
+	"testing"
+
+	"coldcase/pkg/runner/runnertest"
+)
+
+func tool(t *testing.T, name string) *TimelineTool {
+	t.Helper()
+	for _, tl := range Tools() {
+		if tl.name == name {
+			return tl
+		}
+	}
+	t.Fatalf("no tool %s", name)
+	return nil
+}
+
+func TestPlasoArgumentPaths(t *testing.T) {
+	runnertest.Check(t, []runnertest.Case{
+		{
+			Name:   "--flag=value storage file and source directory",
+			Files:  []string{"image/disk.dd"},
+			Args:   []string{"--storage-file=case.plaso", "image"},
+			Want:   []string{"--storage-file=/data/out0/case.plaso", "/data/vol1"},
+			Mounts: []string{"$D/case.plaso:/data/out0/case.plaso:rw", "$D/image:/data/vol1:ro"},
+		},
+		{
+			Name:   "underscore spelling",
+			Files:  []string{"disk.E01"},
+			Args:   []string{"--storage_file", "out/case.plaso", "disk.E01"},
+			Want:   []string{"--storage_file", "/data/out0/case.plaso", "/data/vol1/disk.E01"},
+			Mounts: []string{"$D/out/case.plaso:/data/out0/case.plaso:rw", "$D:/data/vol1:ro"},
+		},
+	}, tool(t, "log2timeline").Run)
+
+	runnertest.Check(t, []runnertest.Case{{
+		Name:   "psort output file",
+		Files:  []string{"case.plaso"},
+		Args:   []string{"-o", "dynamic", "-w", "timeline.csv", "case.plaso"},
+		Want:   []string{"-o", "dynamic", "-w", "/data/out0/timeline.csv", "/data/vol1/case.plaso"},
+		Mounts: []string{"$D/timeline.csv:/data/out0/timeline.csv:rw", "$D:/data/vol1:ro"},
+	}}, tool(t, "psort").Run)
+}
@@ -6,10 +6,14 @@ import (
 	"os"
 	"path/filepath"
 
+	"coldcase/pkg/registry"
 	"coldcase/pkg/runner"
-	"coldcase/pkg/tools"
 )
 
+// VolDir is the directory containing vol.py. The CLI sets it from the
+// resolved configuration.
+var VolDir = "volatility3"
+
 // Volatility3Tool wraps a single Volatility3 plugin or top-level command.
 type Volatility3Tool struct {
 	name        string
@@ -28,10 +32,9 @@ func (v Volatility3Tool) Description() string { return v.description }
 // Command returns the volatility3 plugin/sub-command name.
 func (v Volatility3Tool) Command() string { return v.command }
 
-// Run executes vol.py with the configured plugin and any extra arguments.
-// volDir is the path to the directory containing vol.py (default: "./volatility3").
+// Run executes VolDir/vol.py with the configured plugin and any extra arguments.
 func (v Volatility3Tool) Run(args []string) error {
-	return RunWithVolDir("volatility3", v.command, args)
+	return RunWithVolDir(VolDir, v.command, args)
 }
 
 // RunWithVolDir runs vol.py located at volDir/vol.py with the given plugin and args.
@@ -45,9 +48,26 @@ func RunWithVolDir(volDir, command string, args []string) error {
 	return runner.Run(runner.RunOpts{
 		Binary: "python3",
 		Args:   cmdArgs,
+		// vol.py has no --version; its help output starts with the framework banner.
+		VersionArgs: []string{volPath, "-h"},
+		Outputs:     outputArgs,
+		Embedded:    embeddedPaths,
 	})
 }
 
+// outputArgs are the vol.py options that name the directory plugins such as
+// windows.dumpfiles write extracted files into.
+var outputArgs = []runner.OutputArg{
+	{Flag: "-o", Dir: true},
+	{Flag: "--output-dir", Dir: true},
+}
+
+// embeddedPaths covers vol.py options that take file:// URLs instead of paths.
+var embeddedPaths = []runner.EmbeddedPath{
+	{Flag: "--single-location", Prefix: "file://"},
+	{Flag: "--single-swap-locations", Prefix: "file://"},
+}
+
 // Tools returns the full list of pre-defined Volatility3 tools.
 func Tools() []Volatility3Tool {
 	return []Volatility3Tool{
@@ -87,14 +107,23 @@ func Tools() []Volatility3Tool {
 	}
 }
 
-// CheckDependencies returns a map of dependency name → installed status
-// relevant to the Volatility3 package.
-func CheckDependencies() map[string]bool {
-	deps := map[string]bool{
-		"python3": tools.CheckToolInstalled("python3"),
+// Register adds the Volatility3 plugins to r.
+func Register(r *registry.Registry) {
+	deps := []registry.Dependency{{
+		Name: "volatility3",
+		Check: func() bool {
+			_, err := os.Stat(filepath.Join(VolDir, "vol.py"))
+			return err == nil
+		},
+	}}
+	for _, t := range Tools() {
+		r.Add(registry.Entry{
+			Name:        t.name,
+			Binary:      "python3",
+			Category:    "Volatility3 Memory Forensics",
+			Description: t.description,
+			Deps:        deps,
+			Tool:        t,
+		})
 	}
-	volPath := filepath.Join("volatility3", "vol.py")
-	_, err := os.Stat(volPath)
-	deps["volatility3"] = err == nil
-	return deps
 }
@@ -0,0 +1,33 @@
+package volatility3
+
+import (
+	"testing"
+
+	"coldcase/pkg/runner/runnertest"
+)
+
+func TestVolArgumentPaths(t *testing.T) {
+	runnertest.Check(t, []runnertest.Case{
+		{
+			Name:   "bare relative image and plugin name",
+			Files:  []string{"mem.raw"},
+			Args:   []string{"-f", "mem.raw", "windows.pslist"},
+			Want:   []string{"volatility3/vol.py", "-f", "/data/vol0/mem.raw", "windows.pslist"},
+			Mounts: []string{"$D:/data/vol0:ro"},
+		},
+		{
+			Name:   "file:// location and output directory",
+			Files:  []string{"images/mem.raw"},
+			Args:   []string{"--single-location", "file://images/mem.raw", "-o", "dumps", "windows.dumpfiles"},
+			Want:   []string{"volatility3/vol.py", "--single-location", "file:///data/vol0/mem.raw", "-o", "/data/out1", "windows.dumpfiles"},
+			Mounts: []string{"$D/images:/data/vol0:ro", "$D/dumps:/data/out1:rw"},
+		},
+		{
+			Name:   "--flag=value output directory and swap location",
+			Files:  []string{"mem.raw", "swap/pagefile.sys"},
+			Args:   []string{"-f", "mem.raw", "--single-swap-locations", "file://swap/pagefile.sys", "--output-dir=out", "windows.memmap"},
+			Want:   []string{"volatility3/vol.py", "-f", "/data/vol0/mem.raw", "--single-swap-locations", "file:///data/vol1/pagefile.sys", "--output-dir=/data/out2", "windows.memmap"},
+			Mounts: []string{"$D:/data/vol0:ro", "$D/swap:/data/vol1:ro", "$D/out:/data/out2:rw"},
+		},
+	}, func(args []string) error { return RunWithVolDir("volatility3", "", args) })
+}
@@ -3,6 +3,7 @@
 package windows
 
 import (
+	"coldcase/pkg/registry"
 	"coldcase/pkg/runner"
 )
 
@@ -35,3 +36,16 @@ func Tools() []*WindowsTool {
 		{"registry-dump", "python-registry", "Read/walk Windows Registry hives"},
 	}
 }
+
+// Register adds the Windows artifact tools to r.
+func Register(r *registry.Registry) {
+	for _, t := range Tools() {
+		r.Add(registry.Entry{
+			Name:        t.name,
+			Binary:      t.bin,
+			Category:    "Windows Artifacts",
+			Description: t.desc,
+			Tool:        t,
+		})
+	}
+}